@@ -14,6 +14,9 @@
 	al ejecutar prestar atención al orden en que se despliega el workerId o el url y
 	a la cantidad de segundos que duró la ejecución (con go test -bench)
 
+	por el canal no viaja un string ya formateado sino un result, y es la gorutina padre la que lo registra
+	con logs estructurados (log/slog). con los flags -log-format=json y -log-level=debug se cambia el formato y el nivel
+
 	el ejemplo se adapta de https://github.com/quii/learn-go-with-tests/tree/main/concurrency
 	y el libro Concurrency in Go por Katherine Cox-Buday
 	*/
@@ -21,18 +24,50 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
-	"net/http"
+	"log/slog"
+	"os"
+	"os/signal"
+	"time"
+
+	"arqsoft/tacticas-arq-go/performance/concurrencia/checker"
 )
 
+// logger de la ejecución, main lo configura con los flags -log-format y -log-level
+var logger = slog.Default()
+
+// prober de cada url, main le agrega las tácticas de los flags (ver checker)
+var prober checker.Prober = checker.NewHeadProber(nil)
+
+// result es lo que cada gorutina le pasa por el canal a la gorutina padre
+type result struct {
+	checker.Result
+	workerId int
+	message  string
+}
+
 // esta función es la que llama a las goroutinas y las sincroniza
 // utilizando sync.WaitGroups
-func CheckWebsites(urls []string)  {
+// si se cancela ctx los HEAD en curso se cortan, cada gorutina igual pasa su resultado (cancelado) por el
+// canal: si esta función dejara de leer antes, las gorutinas quedarían bloqueadas para siempre en el envío
+func CheckWebsites(ctx context.Context, urls []string)  {
 	
+	// se normaliza la lista, ver checker.PrepareURLs
+	prepared := checker.PrepareURLs(urls)
+	prepared.Log(logger)
+	urls = prepared.URLs
+
+	// al final se registra el resumen, ver checker.Summary
+	var summary checker.Summary
+	summary.Add(prepared.Invalid...)
+	defer summary.Log(logger)
+
 	var workers int = 1;
 
-	// se crea un canal que permite pasar strings https://gobyexample.com/channels
-	resultStream := make(chan string)
+	// se crea un canal que permite pasar results https://gobyexample.com/channels
+	resultStream := make(chan result)
 
 	// recorremos el slice de urls y lanzamos una gorutina para que verifiquen en forma concurrente los
 	// sitios. el resultado se pasa por el canal resultStrean. 
@@ -40,15 +75,16 @@ func CheckWebsites(urls []string)  {
 	// las gorutinas! o sea, el siguiente for .... se ejecuta concurremente sacando los datos del canal
 	for _, url := range urls {
 		
-		go CheckOneWebsite(url, workers, resultStream)
+		go CheckOneWebsite(ctx, url, workers, resultStream)
 		workers++
 	}
 	
 	//este for se comienza a ejecutar luego de haberse lanzado las gorutinas
 	// va sacando del canal a media que las gorutinas agregan
 	for i := 0; i < len(urls); i++ { 
-		strVal := <- resultStream
-		fmt.Printf("%s ",strVal)
+		res := <- resultStream
+		logResult(res)
+		summary.Add(res.Result)
 	}
 	//se cierra el canal porque no se usa mas, todas las gorutinas terminaron y esta 
 	//ya desplegó todos los datos
@@ -57,28 +93,60 @@ func CheckWebsites(urls []string)  {
 
 
 
-// esta función hace HEAD de el URL e imprime si responde o no
+// registra en el log el resultado que llegó por el canal
+func logResult(res result) {
+	urlLogger := logger.With(checker.KeyStage, "CheckOneWebsite", checker.KeyWorkerID, res.workerId)
+	if res.Err != nil {
+		urlLogger.Warn(res.message, res.Attrs()...)
+	} else {
+		urlLogger.Info(res.message, res.Attrs()...)
+	}
+}
+
+// esta función hace HEAD de el URL y pasa por el canal si responde o no
 // notar el último parametro que es un canal de escritura
-func CheckOneWebsite(url string,  workerId int, results chan<-string)  {
+func CheckOneWebsite(ctx context.Context, url string,  workerId int, results chan<-result)  {
 	
 	// el que sigue es el mismo código que en el ejemplo secuencial, solo que con un canal
-	// las funcion Sprintf crea un string que se pasa al canal
-	response, err := http.Head(url)
-	if err != nil {
-		results <- fmt.Sprintf("workerid: %d para url %s no existe \n", workerId, url)
+	// se arma un result que se pasa al canal
+	res := result{Result: prober.Probe(ctx, url), workerId: workerId}
+	if res.Skipped() {
+		// no se verificó, por ejemplo porque el circuito del host está abierto
+		res.message = res.Err.Error()
+	} else if res.Err != nil {
+		res.message = res.Kind().Message()
 	} else { 
 
-		// ok Head sin error, si no es ok retorna url:false si es ok url:true
-		if response.StatusCode != http.StatusOK {
-			results <- fmt.Sprintf("workerid: %d para url %s resulta False \n", workerId, url)
+		// ok Head sin error, si no cumple los criterios de éxito retorna url:false, si los cumple url:true
+		if !res.OK() {
+			res.message = "resulta False"
 		} else {
-			results <- fmt.Sprintf("workerid: %d para url %s resulta True \n", workerId, url)
+			res.message = "resulta True"
 		}
 	}
+	results <- res
 }
 
 // solo llama a la función de verificar sitios con un slice de urls
 func main() {
+	logConfig := checker.RegisterLogFlags(flag.CommandLine)
+	probeConfig := checker.RegisterProbeFlags(flag.CommandLine)
+	// urls de -inventory y -sitemap, ver checker.Inventory
+	inventory := checker.RegisterInventoryFlag(flag.CommandLine, probeConfig)
+	// profiling y modo daemon, ver checker/profile.go
+	profileConfig := checker.RegisterProfileFlags(flag.CommandLine)
+	flag.Parse()
+
+	var err error
+	if logger, err = logConfig.Logger(os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if prober, err = probeConfig.Prober(logger); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
 		// declara un array de urls para chequear
 		var websites = []string {
 			"http://ort.edu.uy",
@@ -90,9 +158,32 @@ func main() {
 			"http://ingsoft.gaston.com",
 		}
 
-	fmt.Printf("*****comienzo *****\n")
+	urls, err := inventory.URLs(websites)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	profiler, err := profileConfig.Start(logger)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	logger.Info("*****comienzo *****")
+	start := time.Now()
 
-	CheckWebsites(websites)
+	// con ctrl-c se cancelan las verificaciones que faltan
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	profileConfig.Loop(ctx, func() { CheckWebsites(ctx, urls) })
 
-	fmt.Printf("***** FIN *****")
+	logger.Info("***** FIN *****", checker.KeyDuration, time.Since(start))
+
+	if err := probeConfig.Close(); err != nil {
+		logger.Warn("no se pudo guardar la cache", checker.KeyError, err)
+	}
+	if err := profiler.Stop(); err != nil {
+		logger.Warn("no se pudieron guardar los perfiles", checker.KeyError, err)
+	}
 }
\ No newline at end of file
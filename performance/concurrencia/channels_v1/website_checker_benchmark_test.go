@@ -4,7 +4,16 @@
 
 package main
 
-import("testing")
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"arqsoft/tacticas-arq-go/performance/concurrencia/checker"
+	"arqsoft/tacticas-arq-go/performance/concurrencia/checker/checkertest"
+)
 
 
 
@@ -26,7 +35,55 @@ func BenchmarkWebsiteChecker(b *testing.B) {
 	   // hace el benchmark hasta que N que es un valor que el runtime determina para que sea significativo
 	   // Demora tenga paciencia!
 	   //
-	for i:=1 ; i < b.N; i++ {
-		CheckWebsites(websites)
+	for i := 0; i < b.N; i++ {
+		CheckWebsites(context.Background(), websites)
+	}
+}
+
+// benchmark de la táctica de hedging contra un servidor local en el que 1 de cada 20 pedidos demora 300ms.
+// compara el prober sin tácticas con el que lanza un segundo HEAD pasado el p90 de las latencias observadas,
+// ver los percentiles p50-ns y p99-ns del tiempo de cada ejecución
+// go test -bench TailLatency
+func BenchmarkTailLatency(b *testing.B) {
+	server := checkertest.NewServer(checkertest.TailLatency(2*time.Millisecond, 300*time.Millisecond, 20))
+	defer server.Close()
+	urls := checkertest.URLs(server, 20)
+
+	savedLogger, savedProber := logger, prober
+	defer func() { logger, prober = savedLogger, savedProber }()
+	logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	strategies := []struct {
+		name   string
+		prober checker.Prober
+	}{
+		{"plain", checker.NewHeadProber(server.Client())},
+		{"hedged", checker.Chain(checker.NewHeadProber(server.Client()),
+			checker.Hedge(checker.HedgeConfig{Percentile: 90, Delay: 20 * time.Millisecond}))},
 	}
-}
\ No newline at end of file
+
+	for _, s := range strategies {
+		b.Run(s.name, func(b *testing.B) {
+			prober = s.prober
+			durations := make([]time.Duration, 0, b.N)
+			for i := 0; i < b.N; i++ {
+				start := time.Now()
+				CheckWebsites(context.Background(), urls)
+				durations = append(durations, time.Since(start))
+			}
+			checkertest.ReportPercentiles(b, durations)
+		})
+	}
+}
+
+// benchmark de escalabilidad con un prober falso, sin salir a la red: verifica 10, 100, 1000 y 10000 urls con
+// cada perfil de latencia de checkertest y reporta allocations, urls/s y max-inflight. para guardar los
+// resultados en el formato de benchstat
+// go test -bench Scalability -count 6 | tee resultados.txt
+func BenchmarkScalability(b *testing.B) {
+	savedLogger, savedProber := logger, prober
+	defer func() { logger, prober = savedLogger, savedProber }()
+	logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	checkertest.BenchmarkWorkloads(b, func(p checker.Prober) { prober = p }, CheckWebsites)
+}
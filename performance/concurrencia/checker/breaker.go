@@ -0,0 +1,218 @@
+package checker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+/*
+	circuit breaker por host (táctica de disponibilidad).
+
+	cuando un host está caído cada url de ese host espera el timeout completo del HEAD. el breaker cuenta las
+	fallas consecutivas de cada host y, al llegar al umbral, "abre el circuito": los urls de ese host no se
+	verifican y se reportan como salteados hasta que pasa el tiempo de enfriamiento.
+
+		closed ---(FailureThreshold fallas seguidas)---> open ---(CoolDown)---> half-open
+		  ^                                                ^                        |
+		  |                                                +-------(falla)----------+
+		  +--------------------------(éxito)-----------------------------------------+
+
+	en half-open se dejan pasar HalfOpenProbes verificaciones de prueba, si alguna falla se vuelve a abrir
+	y si responden se cierra. solo cuentan como fallas los errores del HEAD (el host no respondió), un status
+	distinto de 200 significa que el host está vivo
+*/
+
+// SkipError indica que el url no se verificó y por qué
+type SkipError struct {
+	Reason string
+}
+
+func (e *SkipError) Error() string {
+	return "skipped: " + e.Reason
+}
+
+// ErrCircuitOpen es el error de los resultados de urls que no se verificaron porque el circuito del host está abierto
+var ErrCircuitOpen = &SkipError{Reason: "circuit open"}
+
+// Skipped indica si el url no se llegó a verificar
+func (r Result) Skipped() bool {
+	var skip *SkipError
+	return errors.As(r.Err, &skip)
+}
+
+type BreakerState int
+
+const (
+	BreakerClosed BreakerState = iota
+	BreakerOpen
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// BreakerConfig es la configuración de los circuit breakers, con FailureThreshold 0 no se usan
+type BreakerConfig struct {
+	// fallas consecutivas de un host para abrir su circuito
+	FailureThreshold int
+	// tiempo que el circuito queda abierto antes de pasar a half-open
+	CoolDown time.Duration
+	// verificaciones de prueba que se permiten en half-open
+	HalfOpenProbes int
+}
+
+// Breakers mantiene un circuit breaker por host
+type Breakers struct {
+	cfg BreakerConfig
+
+	// OnStateChange si no es nil se llama en cada cambio de estado de un circuito
+	OnStateChange func(host string, from, to BreakerState)
+
+	now func() time.Time
+
+	mu    sync.Mutex
+	hosts map[string]*breaker
+}
+
+type breaker struct {
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	trials   int
+}
+
+type outcome int
+
+const (
+	outcomeSuccess outcome = iota
+	outcomeFailure
+	// la verificación no dice nada del host (por ejemplo se canceló el context)
+	outcomeNeutral
+)
+
+func NewBreakers(cfg BreakerConfig) *Breakers {
+	if cfg.HalfOpenProbes < 1 {
+		cfg.HalfOpenProbes = 1
+	}
+	return &Breakers{cfg: cfg, now: time.Now, hosts: map[string]*breaker{}}
+}
+
+// State devuelve el estado actual del circuito del host
+func (b *Breakers) State(host string) BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if br, ok := b.hosts[host]; ok {
+		return br.state
+	}
+	return BreakerClosed
+}
+
+// allow indica si se puede verificar un url del host
+func (b *Breakers) allow(host string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	br := b.breaker(host)
+	switch br.state {
+	case BreakerOpen:
+		if b.now().Sub(br.openedAt) < b.cfg.CoolDown {
+			return false
+		}
+		b.transition(host, br, BreakerHalfOpen)
+		br.trials = 0
+		fallthrough
+	case BreakerHalfOpen:
+		if br.trials >= b.cfg.HalfOpenProbes {
+			return false
+		}
+		br.trials++
+	}
+	return true
+}
+
+// record registra el resultado de una verificación que allow dejó pasar
+func (b *Breakers) record(host string, o outcome) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	br := b.breaker(host)
+	switch br.state {
+	case BreakerClosed:
+		switch o {
+		case outcomeSuccess:
+			br.failures = 0
+		case outcomeFailure:
+			br.failures++
+			if br.failures >= b.cfg.FailureThreshold {
+				b.open(host, br)
+			}
+		}
+	case BreakerHalfOpen:
+		switch o {
+		case outcomeSuccess:
+			br.failures = 0
+			b.transition(host, br, BreakerClosed)
+		case outcomeFailure:
+			b.open(host, br)
+		case outcomeNeutral:
+			br.trials--
+		}
+	}
+}
+
+func (b *Breakers) breaker(host string) *breaker {
+	br, ok := b.hosts[host]
+	if !ok {
+		br = &breaker{}
+		b.hosts[host] = br
+	}
+	return br
+}
+
+func (b *Breakers) open(host string, br *breaker) {
+	br.openedAt = b.now()
+	b.transition(host, br, BreakerOpen)
+}
+
+func (b *Breakers) transition(host string, br *breaker, to BreakerState) {
+	from := br.state
+	br.state = to
+	if b.OnStateChange != nil && from != to {
+		b.OnStateChange(host, from, to)
+	}
+}
+
+// CircuitBreaker es el Middleware que no deja verificar urls de hosts con el circuito abierto
+func CircuitBreaker(b *Breakers) Middleware {
+	return func(next Prober) Prober {
+		return ProberFunc(func(ctx context.Context, rawURL string) Result {
+			host := Host(rawURL)
+			if !b.allow(host) {
+				return Result{URL: rawURL, Attempt: 1, Err: ErrCircuitOpen}
+			}
+
+			res := next.Probe(ctx, rawURL)
+			switch {
+			case res.Err == nil:
+				b.record(host, outcomeSuccess)
+			case ctx.Err() != nil || res.Skipped():
+				b.record(host, outcomeNeutral)
+			default:
+				b.record(host, outcomeFailure)
+			}
+			return res
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package checker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// prober de prueba que falla o responde según la variable down y cuenta los llamados
+type flakyProber struct {
+	down  bool
+	calls int
+}
+
+func (p *flakyProber) Probe(ctx context.Context, url string) Result {
+	p.calls++
+	if p.down {
+		return Result{URL: url, Attempt: 1, Err: errors.New("connection refused")}
+	}
+	return Result{URL: url, Attempt: 1, StatusCode: 200}
+}
+
+func TestCircuitBreakerOpensAndRecovers(t *testing.T) {
+	now := time.Now()
+	breakers := NewBreakers(BreakerConfig{FailureThreshold: 2, CoolDown: time.Minute})
+	breakers.now = func() time.Time { return now }
+
+	var transitions []string
+	breakers.OnStateChange = func(host string, from, to BreakerState) {
+		transitions = append(transitions, from.String()+"->"+to.String())
+	}
+
+	base := &flakyProber{down: true}
+	prober := CircuitBreaker(breakers)(base)
+	ctx := context.Background()
+
+	prober.Probe(ctx, "http://caido.com/a")
+	prober.Probe(ctx, "http://caido.com/b")
+	if s := breakers.State("caido.com"); s != BreakerOpen {
+		t.Fatalf("luego de 2 fallas el circuito debe estar abierto, está %s", s)
+	}
+
+	res := prober.Probe(ctx, "http://caido.com/c")
+	if !errors.Is(res.Err, ErrCircuitOpen) || !res.Skipped() {
+		t.Errorf("con el circuito abierto se esperaba skipped: circuit open, se obtuvo %+v", res)
+	}
+	if base.calls != 2 {
+		t.Errorf("con el circuito abierto no se debe llamar al HEAD, llamados = %d", base.calls)
+	}
+	if res := prober.Probe(ctx, "http://otro.com"); res.Err != nil && res.Skipped() {
+		t.Errorf("el circuito es por host, otro host no debe quedar salteado")
+	}
+
+	// pasado el enfriamiento se deja pasar una prueba, si falla se vuelve a abrir
+	now = now.Add(time.Minute)
+	base.calls = 0
+	prober.Probe(ctx, "http://caido.com/d")
+	if s := breakers.State("caido.com"); s != BreakerOpen || base.calls != 1 {
+		t.Fatalf("la prueba en half-open falló, el circuito debe volver a open (está %s, llamados %d)", s, base.calls)
+	}
+
+	// la siguiente prueba responde y el circuito se cierra
+	now = now.Add(time.Minute)
+	base.down = false
+	if res := prober.Probe(ctx, "http://caido.com/e"); !res.OK() {
+		t.Fatalf("se esperaba OK: %+v", res)
+	}
+	if s := breakers.State("caido.com"); s != BreakerClosed {
+		t.Errorf("luego de una prueba exitosa el circuito debe cerrarse, está %s", s)
+	}
+
+	want := []string{"closed->open", "open->half-open", "half-open->open", "open->half-open", "half-open->closed"}
+	if len(transitions) != len(want) {
+		t.Fatalf("transiciones = %v, se esperaba %v", transitions, want)
+	}
+	for i := range want {
+		if transitions[i] != want[i] {
+			t.Errorf("transiciones = %v, se esperaba %v", transitions, want)
+			break
+		}
+	}
+}
+
+func TestCircuitBreakerHalfOpenLimitsTrials(t *testing.T) {
+	now := time.Now()
+	breakers := NewBreakers(BreakerConfig{FailureThreshold: 1, CoolDown: time.Second})
+	breakers.now = func() time.Time { return now }
+
+	breakers.allow("h")
+	breakers.record("h", outcomeFailure)
+	now = now.Add(time.Second)
+
+	if !breakers.allow("h") {
+		t.Fatal("pasado el enfriamiento se debe permitir una prueba")
+	}
+	if breakers.allow("h") {
+		t.Error("en half-open solo se permite HalfOpenProbes pruebas a la vez")
+	}
+	// una prueba cancelada libera el lugar sin cambiar el estado
+	breakers.record("h", outcomeNeutral)
+	if !breakers.allow("h") {
+		t.Error("una prueba neutral debe liberar su lugar en half-open")
+	}
+}
@@ -0,0 +1,291 @@
+package checker
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+/*
+	cache de resultados (táctica de performance "maintain multiple copies of data").
+
+	cuando un url aparece varias veces en la lista, o se hacen varias ejecuciones seguidas, cada estrategia lo
+	vuelve a verificar. la cache guarda el resultado de cada url durante TTL y lo devuelve sin hacer el HEAD.
+	además, si varias gorutinas verifican el mismo url al mismo tiempo, solo una hace el HEAD y las demás esperan
+	y comparten su resultado (como singleflight), así no se hacen pedidos duplicados aunque el url todavía no
+	esté en la cache.
+
+	opcionalmente la cache se guarda en un archivo JSON al terminar y se vuelve a cargar en la siguiente ejecución.
+
+	los resultados se guardan ya evaluados contra los criterios de éxito (Unmet), por eso cada entrada se guarda
+	por url y por una huella de los criterios y de la configuración del pedido (ver Variant): si en la siguiente
+	ejecución cambian -expect, -expect-status o los headers el url se vuelve a verificar.
+*/
+
+// CacheConfig es la configuración de la cache, con TTL 0 no se usa
+type CacheConfig struct {
+	TTL time.Duration
+	// archivo donde se persiste la cache entre ejecuciones, vacío para tenerla solo en memoria
+	Path string
+}
+
+// Cache guarda los resultados por url y coordina las verificaciones en vuelo
+type Cache struct {
+	cfg CacheConfig
+	now func() time.Time
+	// Variant, si no es nil, devuelve la huella de lo que hace a la evaluación de un url (ver Variant)
+	Variant func(url string) string
+
+	mu       sync.Mutex
+	entries  map[string]cacheEntry
+	inFlight map[string]*flight
+}
+
+type cacheEntry struct {
+	result  Result
+	variant string
+	expires time.Time
+}
+
+// flight es una verificación en vuelo, done se cierra cuando res tiene el resultado
+type flight struct {
+	done chan struct{}
+	res  Result
+	// completed es false si la verificación terminó en pánico y res no tiene un resultado
+	completed bool
+}
+
+// NewCache crea la cache y, si tiene Path, carga las entradas que no vencieron
+func NewCache(cfg CacheConfig) (*Cache, error) {
+	c := &Cache{cfg: cfg, now: time.Now, entries: map[string]cacheEntry{}, inFlight: map[string]*flight{}}
+	if cfg.Path == "" {
+		return c, nil
+	}
+	if err := c.load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Probe devuelve el resultado de la cache o, si no está o venció, verifica el url con next
+func (c *Cache) Probe(ctx context.Context, url string, next Prober) Result {
+	return c.probe(ctx, url, c.variant(url), next)
+}
+
+func (c *Cache) probe(ctx context.Context, url, variant string, next Prober) Result {
+	key := cacheKey(url, variant)
+	c.mu.Lock()
+	if e, ok := c.entries[key]; ok && c.now().Before(e.expires) {
+		c.mu.Unlock()
+		res := e.result
+		res.Cached = true
+		return res
+	}
+	if f, ok := c.inFlight[key]; ok {
+		c.mu.Unlock()
+		return c.wait(ctx, url, variant, f, next)
+	}
+	f := &flight{done: make(chan struct{})}
+	c.inFlight[key] = f
+	c.mu.Unlock()
+
+	// en un defer para que, aunque next entre en pánico, el url deje de estar en vuelo y los que esperan sigan
+	defer func() {
+		c.mu.Lock()
+		delete(c.inFlight, key)
+		if f.completed && cacheable(ctx, f.res) {
+			c.entries[key] = cacheEntry{result: f.res, variant: variant, expires: c.now().Add(c.cfg.TTL)}
+		}
+		c.mu.Unlock()
+		close(f.done)
+	}()
+
+	f.res = next.Probe(ctx, url)
+	f.completed = true
+	return f.res
+}
+
+func (c *Cache) variant(url string) string {
+	if c.Variant == nil {
+		return ""
+	}
+	return c.Variant(url)
+}
+
+// cacheKey es la clave de la entrada, el url con la huella como fragmento (los urls normalizados no tienen)
+func cacheKey(url, variant string) string {
+	if variant == "" {
+		return url
+	}
+	return url + "#" + variant
+}
+
+// Variant devuelve la huella de los criterios de éxito y de la configuración del pedido de un url. en lugar
+// de los valores se guarda su sha256, así los secretos de la configuración no quedan en el archivo de la cache
+func Variant(exp Expectation, req *RequestConfig) string {
+	h := sha256.New()
+	// fmt imprime los maps ordenados por clave, así la huella no depende del orden de los headers
+	fmt.Fprintf(h, "%s|%t|%s|%d|%s|%d|%q|%v|%v|%s", exp.Status, exp.NoRedirects, exp.RedirectTo, exp.MaxDuration,
+		exp.method(), exp.MaxBodyBytes, exp.BodyContains, exp.Headers, exp.JSON, exp.BodySHA256)
+	for _, re := range exp.BodyMatches {
+		fmt.Fprintf(h, "|%s", re)
+	}
+	if req != nil {
+		json.NewEncoder(h).Encode(req)
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// wait espera el resultado de la verificación en vuelo de otra gorutina
+func (c *Cache) wait(ctx context.Context, url, variant string, f *flight, next Prober) Result {
+	select {
+	case <-f.done:
+	case <-ctx.Done():
+		return Result{URL: url, Attempt: 1, Err: ctx.Err()}
+	}
+
+	// si al que hizo el HEAD le cancelaron el context o entró en pánico el resultado no sirve, se vuelve a intentar
+	if !f.completed || ctx.Err() == nil && isContextError(f.res.Err) {
+		return c.probe(ctx, url, variant, next)
+	}
+	res := f.res
+	res.Shared = true
+	return res
+}
+
+// cacheable indica si el resultado se puede reutilizar, los urls salteados o cancelados no dicen nada del sitio
+func cacheable(ctx context.Context, res Result) bool {
+	return !res.Skipped() && !isContextError(res.Err) && ctx.Err() == nil
+}
+
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// Cached es el Middleware que pone la cache delante de la verificación
+func Cached(c *Cache) Middleware {
+	return func(next Prober) Prober {
+		return ProberFunc(func(ctx context.Context, url string) Result {
+			return c.Probe(ctx, url, next)
+		})
+	}
+}
+
+// CachedScope es como Cached pero guarda los resultados separados de los de otros probers del mismo url,
+// por ejemplo las páginas que descarga el crawler de las verificaciones con HEAD
+func CachedScope(c *Cache, scope string) Middleware {
+	return func(next Prober) Prober {
+		return ProberFunc(func(ctx context.Context, url string) Result {
+			return c.probe(ctx, url, scope+":"+c.variant(url), next)
+		})
+	}
+}
+
+// persistedEntry es como se guarda cada entrada en el archivo, el error se guarda como texto
+type persistedEntry struct {
+	URL        string        `json:"url"`
+	Variant    string        `json:"variant,omitempty"`
+	StatusCode int           `json:"status_code,omitempty"`
+	Duration   time.Duration `json:"duration"`
+	Err        string        `json:"error,omitempty"`
+	ErrKind    string        `json:"error_kind,omitempty"`
+	Method     string        `json:"method,omitempty"`
+	FinalURL   string        `json:"final_url,omitempty"`
+	Redirects  []Redirect    `json:"redirects,omitempty"`
+	Evaluated  bool          `json:"evaluated,omitempty"`
+	Unmet      []string      `json:"unmet,omitempty"`
+	Answers    []string      `json:"answers,omitempty"`
+	Health     string        `json:"health,omitempty"`
+	Links      []string      `json:"links,omitempty"`
+	TLS        *TLSInfo      `json:"tls,omitempty"`
+	Expires    time.Time     `json:"expires"`
+}
+
+// Save guarda en el archivo de la cache las entradas que no vencieron
+func (c *Cache) Save() error {
+	if c.cfg.Path == "" {
+		return nil
+	}
+
+	c.mu.Lock()
+	now := c.now()
+	entries := make([]persistedEntry, 0, len(c.entries))
+	for _, e := range c.entries {
+		if !now.Before(e.expires) {
+			continue
+		}
+		p := persistedEntry{
+			URL:        e.result.URL,
+			Variant:    e.variant,
+			StatusCode: e.result.StatusCode,
+			Duration:   e.result.Duration,
+			Method:     e.result.Method,
+			FinalURL:   e.result.FinalURL,
+			Redirects:  e.result.Redirects,
+			Evaluated:  e.result.Evaluated,
+			Unmet:      e.result.Unmet,
+			Answers:    e.result.Answers,
+			Health:     e.result.Health,
+			Links:      e.result.Links,
+			TLS:        e.result.TLS,
+			Expires:    e.expires,
+		}
+		if e.result.Err != nil {
+			p.Err = e.result.Err.Error()
+			p.ErrKind = e.result.Kind().String()
+		}
+		entries = append(entries, p)
+	}
+	c.mu.Unlock()
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(c.cfg.Path, data, 0o644)
+}
+
+func (c *Cache) load() error {
+	data, err := os.ReadFile(c.cfg.Path)
+	if err != nil {
+		return err
+	}
+	var entries []persistedEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return err
+	}
+
+	now := c.now()
+	for _, p := range entries {
+		if !now.Before(p.Expires) {
+			continue
+		}
+		res := Result{
+			URL:        p.URL,
+			StatusCode: p.StatusCode,
+			Duration:   p.Duration,
+			Attempt:    1,
+			Method:     p.Method,
+			FinalURL:   p.FinalURL,
+			Redirects:  p.Redirects,
+			Evaluated:  p.Evaluated,
+			Unmet:      p.Unmet,
+			Answers:    p.Answers,
+			Health:     p.Health,
+			Links:      p.Links,
+			TLS:        p.TLS,
+		}
+		if p.Err != "" {
+			// el error vuelve como texto, la categoría se guarda aparte para no perderla
+			res.Err = &ProbeError{Kind: ParseErrorKind(p.ErrKind), Err: errors.New(p.Err)}
+		}
+		c.entries[cacheKey(p.URL, p.Variant)] = cacheEntry{result: res, variant: p.Variant, expires: p.Expires}
+	}
+	return nil
+}
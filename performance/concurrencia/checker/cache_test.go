@@ -0,0 +1,242 @@
+package checker
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCacheReusesResultsUntilTTL(t *testing.T) {
+	var calls int32
+	base := ProberFunc(func(ctx context.Context, url string) Result {
+		atomic.AddInt32(&calls, 1)
+		return Result{URL: url, StatusCode: 200}
+	})
+
+	cache, err := NewCache(CacheConfig{TTL: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+	prober := Cached(cache)(base)
+
+	if res := prober.Probe(context.Background(), "http://a.com"); res.Cached {
+		t.Errorf("la primera verificación no puede salir de la cache: %+v", res)
+	}
+	if res := prober.Probe(context.Background(), "http://a.com"); !res.Cached || !res.OK() {
+		t.Errorf("la segunda verificación debe salir de la cache: %+v", res)
+	}
+	now = now.Add(time.Minute)
+	if res := prober.Probe(context.Background(), "http://a.com"); res.Cached {
+		t.Errorf("vencido el TTL se debe volver a verificar: %+v", res)
+	}
+	if calls != 2 {
+		t.Errorf("HEAD realizados = %d, se esperaban 2", calls)
+	}
+}
+
+func TestCacheCoalescesConcurrentProbes(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	base := ProberFunc(func(ctx context.Context, url string) Result {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return Result{URL: url, StatusCode: 200}
+	})
+
+	cache, _ := NewCache(CacheConfig{TTL: time.Minute})
+	prober := Cached(cache)(base)
+
+	var wg sync.WaitGroup
+	results := make(chan Result, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results <- prober.Probe(context.Background(), "http://a.com")
+		}()
+	}
+	// se espera a que todas las gorutinas estén esperando la verificación en vuelo
+	for {
+		cache.mu.Lock()
+		f := cache.inFlight["http://a.com"]
+		cache.mu.Unlock()
+		if f != nil {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(results)
+
+	for res := range results {
+		if !res.OK() {
+			t.Errorf("todas las gorutinas deben recibir el resultado: %+v", res)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("HEAD realizados = %d, las verificaciones concurrentes del mismo url deben compartir uno", calls)
+	}
+}
+
+// si la verificación en vuelo entra en pánico, los que la esperaban no quedan bloqueados y vuelven a verificar
+func TestCacheRecoversFromPanickingProbe(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	base := ProberFunc(func(ctx context.Context, url string) Result {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			<-release
+			panic("falla del prober")
+		}
+		return Result{URL: url, StatusCode: 200}
+	})
+
+	cache, _ := NewCache(CacheConfig{TTL: time.Minute})
+	prober := Cached(cache)(base)
+
+	panicked := make(chan any, 1)
+	go func() {
+		defer func() { panicked <- recover() }()
+		prober.Probe(context.Background(), "http://a.com")
+	}()
+	for {
+		cache.mu.Lock()
+		f := cache.inFlight["http://a.com"]
+		cache.mu.Unlock()
+		if f != nil {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	waiter := make(chan Result, 1)
+	go func() { waiter <- prober.Probe(context.Background(), "http://a.com") }()
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+
+	if p := <-panicked; p == nil {
+		t.Error("el pánico del prober debía llegar al que hizo la verificación")
+	}
+	select {
+	case res := <-waiter:
+		if !res.OK() || res.Shared {
+			t.Errorf("el que esperaba debía volver a verificar: %+v", res)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("el que esperaba quedó bloqueado luego del pánico")
+	}
+	if res := prober.Probe(context.Background(), "http://a.com"); !res.Cached {
+		t.Errorf("el resultado de la nueva verificación debía quedar en la cache: %+v", res)
+	}
+}
+
+func TestCacheDoesNotKeepSkippedOrCanceledResults(t *testing.T) {
+	cache, _ := NewCache(CacheConfig{TTL: time.Minute})
+	skipped := Cached(cache)(ProberFunc(func(ctx context.Context, url string) Result {
+		return Result{URL: url, Err: ErrCircuitOpen}
+	}))
+	skipped.Probe(context.Background(), "http://a.com")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	canceled := Cached(cache)(ProberFunc(func(ctx context.Context, url string) Result {
+		return Result{URL: url, Err: ctx.Err()}
+	}))
+	canceled.Probe(ctx, "http://b.com")
+
+	if len(cache.entries) != 0 {
+		t.Errorf("no se deben guardar resultados salteados ni cancelados: %v", cache.entries)
+	}
+}
+
+func TestCachePersistsBetweenRuns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.json")
+	cfg := CacheConfig{TTL: time.Hour, Path: path}
+
+	first, err := NewCache(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Cached(first)(ProberFunc(func(ctx context.Context, url string) Result {
+		return Result{URL: url, StatusCode: 404}
+	})).Probe(context.Background(), "http://a.com")
+	if err := first.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := NewCache(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := Cached(second)(ProberFunc(func(ctx context.Context, url string) Result {
+		t.Error("el resultado debía salir de la cache persistida")
+		return Result{}
+	})).Probe(context.Background(), "http://a.com")
+	if !res.Cached || res.StatusCode != 404 {
+		t.Errorf("resultado cargado = %+v", res)
+	}
+}
+
+func TestCacheDoesNotReuseResultsEvaluatedWithOtherCriteria(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.json")
+	cfg := CacheConfig{TTL: time.Hour, Path: path}
+	var calls int32
+	base := ProberFunc(func(ctx context.Context, url string) Result {
+		atomic.AddInt32(&calls, 1)
+		return Result{URL: url, StatusCode: 200}
+	})
+	run := func(exp Expectation, req *RequestConfig) Result {
+		cache, err := NewCache(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cache.Variant = func(url string) string { return Variant(exp, req) }
+		res := Cached(cache)(base).Probe(context.Background(), "http://a.com")
+		if err := cache.Save(); err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	status, _ := ParseStatusSet("200")
+	run(Expectation{Status: status}, nil)
+	if res := run(Expectation{Status: status}, nil); !res.Cached {
+		t.Errorf("con los mismos criterios el resultado debía salir de la cache: %+v", res)
+	}
+	other, _ := ParseStatusSet("204")
+	if res := run(Expectation{Status: other}, nil); res.Cached {
+		t.Errorf("con otros criterios de éxito no se puede reutilizar el resultado: %+v", res)
+	}
+	if res := run(Expectation{Status: status}, &RequestConfig{Bearer: "env:TOKEN"}); res.Cached {
+		t.Errorf("con otra configuración del pedido no se puede reutilizar el resultado: %+v", res)
+	}
+	if calls != 3 {
+		t.Errorf("HEAD realizados = %d, se esperaban 3", calls)
+	}
+}
+
+func TestCachedScopeKeepsResultsApart(t *testing.T) {
+	cache, err := NewCache(CacheConfig{TTL: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	head := Cached(cache)(ProberFunc(func(ctx context.Context, url string) Result {
+		return Result{URL: url, StatusCode: 200}
+	}))
+	pages := CachedScope(cache, "page")(ProberFunc(func(ctx context.Context, url string) Result {
+		return Result{URL: url, StatusCode: 200, Links: []string{url + "/a"}}
+	}))
+
+	head.Probe(context.Background(), "http://a.com")
+	if res := pages.Probe(context.Background(), "http://a.com"); res.Cached || len(res.Links) != 1 {
+		t.Errorf("la página no debía salir del resultado del HEAD: %+v", res)
+	}
+	if res := pages.Probe(context.Background(), "http://a.com"); !res.Cached || len(res.Links) != 1 {
+		t.Errorf("la página con sus links debía salir de la cache: %+v", res)
+	}
+}
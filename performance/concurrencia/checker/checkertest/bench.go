@@ -0,0 +1,53 @@
+package checkertest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"arqsoft/tacticas-arq-go/performance/concurrencia/checker"
+)
+
+// URLCounts son las cantidades de urls de los benchmarks de escalabilidad
+var URLCounts = []int{10, 100, 1000, 10000}
+
+// LatencyProfiles son los perfiles de latencia de los benchmarks de escalabilidad: sin demora (mide solo lo que
+// cuesta la estrategia), 1ms por url y 1ms salvo uno de cada 100 urls que demora 50ms
+var LatencyProfiles = []struct {
+	Name    string
+	Latency LatencyProfile
+}{
+	{"none", Constant(0)},
+	{"1ms", Constant(time.Millisecond)},
+	{"tail", TailLatency(time.Millisecond, 50*time.Millisecond, 100)},
+}
+
+// BenchmarkWorkloads ejecuta check como sub-benchmark para cada cantidad de urls y perfil de latencia, con un
+// FakeProber nuevo que se instala con use. los nombres tienen la forma urls=100/latency=1ms para que benchstat
+// pueda comparar por cada dimensión. además del tiempo y las allocations reporta urls/s y max-inflight, la
+// mayor cantidad de urls que se verificaron a la vez. con -short se saltean las cargas de más de 1000 urls
+func BenchmarkWorkloads(b *testing.B, use func(checker.Prober), check func(context.Context, []string)) {
+	for _, n := range URLCounts {
+		b.Run(fmt.Sprintf("urls=%d", n), func(b *testing.B) {
+			if testing.Short() && n > 1000 {
+				b.Skip("se saltea con -short")
+			}
+			urls, results := FakeSites(n)
+			for _, profile := range LatencyProfiles {
+				b.Run("latency="+profile.Name, func(b *testing.B) {
+					fake := &FakeProber{Latency: profile.Latency, Results: results}
+					use(fake)
+					b.ReportAllocs()
+					b.ResetTimer()
+					for i := 0; i < b.N; i++ {
+						check(context.Background(), urls)
+					}
+					b.StopTimer()
+					b.ReportMetric(float64(b.N*n)/b.Elapsed().Seconds(), "urls/s")
+					b.ReportMetric(float64(fake.MaxInFlight()), "max-inflight")
+				})
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package checkertest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"arqsoft/tacticas-arq-go/performance/concurrencia/checker"
+)
+
+// FakeProber es un prober que no sale a la red, para probar las estrategias de concurrencia sin depender de
+// los sitios. demora Delay (o hasta que se cancele el context) y devuelve el resultado de Results para el url,
+// si no está ahí un 200. registra cuántas veces se verificó cada url y cuántas verificaciones hubo a la vez
+type FakeProber struct {
+	Delay   time.Duration
+	Results map[string]checker.Result
+	// Latency, si no es nil, reemplaza a Delay: la verificación número n demora Latency(n)
+	Latency LatencyProfile
+
+	mu          sync.Mutex
+	calls       map[string]int
+	n           int64
+	inFlight    int
+	maxInFlight int
+}
+
+func (p *FakeProber) Probe(ctx context.Context, url string) checker.Result {
+	p.mu.Lock()
+	if p.calls == nil {
+		p.calls = map[string]int{}
+	}
+	p.calls[url]++
+	p.n++
+	delay := p.Delay
+	if p.Latency != nil {
+		delay = p.Latency(p.n)
+	}
+	p.inFlight++
+	if p.inFlight > p.maxInFlight {
+		p.maxInFlight = p.inFlight
+	}
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		p.inFlight--
+		p.mu.Unlock()
+	}()
+
+	start := time.Now()
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		return checker.Result{URL: url, Attempt: 1, Duration: time.Since(start), Err: ctx.Err()}
+	}
+
+	res, ok := p.Results[url]
+	if !ok {
+		res = checker.Result{StatusCode: 200, Evaluated: true}
+	}
+	res.URL, res.Attempt, res.Duration = url, 1, time.Since(start)
+	return res
+}
+
+// Calls devuelve cuántas veces se verificó cada url
+func (p *FakeProber) Calls() map[string]int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	calls := make(map[string]int, len(p.calls))
+	for url, n := range p.calls {
+		calls[url] = n
+	}
+	return calls
+}
+
+// MaxInFlight devuelve la mayor cantidad de verificaciones que estuvieron en curso a la vez, es decir la
+// concurrencia que alcanzó la estrategia
+func (p *FakeProber) MaxInFlight() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.maxInFlight
+}
+
+// Logs guarda los registros de un logger JSON para que los tests revisen lo que registró un ejemplo
+type Logs struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+// NewLogs devuelve el logger (de nivel info, como el de los ejemplos por defecto) y los registros que guarda
+func NewLogs() (*slog.Logger, *Logs) {
+	logs := &Logs{}
+	return slog.New(slog.NewJSONHandler(logs, nil)), logs
+}
+
+func (l *Logs) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.buf.Write(p)
+}
+
+// String devuelve los registros guardados tal cual se escribieron
+func (l *Logs) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.buf.String()
+}
+
+// Records devuelve los registros guardados, cada uno con sus campos
+func (l *Logs) Records() []map[string]any {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var records []map[string]any
+	for _, line := range bytes.Split(l.buf.Bytes(), []byte("\n")) {
+		var record map[string]any
+		if json.Unmarshal(line, &record) == nil {
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+// Find devuelve el primer registro con el mensaje, nil si no hay
+func (l *Logs) Find(msg string) map[string]any {
+	for _, record := range l.Records() {
+		if record["msg"] == msg {
+			return record
+		}
+	}
+	return nil
+}
+
+// ByURL cuenta los registros de cada url. con el nivel info los ejemplos registran una vez el resultado de cada
+// url, así se puede verificar que ningún url se pierda o se registre dos veces
+func (l *Logs) ByURL() map[string]int {
+	counts := map[string]int{}
+	for _, record := range l.Records() {
+		if url, ok := record[checker.KeyURL].(string); ok {
+			counts[url]++
+		}
+	}
+	return counts
+}
+
+// FakeSites devuelve n urls distintos y los resultados para FakeProber: los múltiplos de 5 responden 503 y los
+// de 7 dan timeout, el resto responde 200
+func FakeSites(n int) ([]string, map[string]checker.Result) {
+	urls := make([]string, 0, n)
+	results := map[string]checker.Result{}
+	for i := 1; i <= n; i++ {
+		url := fmt.Sprintf("http://sitio%d.test", i)
+		urls = append(urls, url)
+		switch {
+		case i%5 == 0:
+			results[url] = checker.Result{StatusCode: 503}
+		case i%7 == 0:
+			results[url] = checker.Result{Err: context.DeadlineExceeded}
+		}
+	}
+	return urls, results
+}
+
+// ExpectOnce hace fallar el test si algún url no aparece exactamente una vez en counts (por ejemplo en
+// FakeProber.Calls o Logs.ByURL), o si aparece alguno que no está en urls
+func ExpectOnce(t testing.TB, what string, urls []string, counts map[string]int) {
+	t.Helper()
+	for _, url := range urls {
+		if counts[url] != 1 {
+			t.Errorf("%s: %s aparece %d veces, se esperaba una", what, url, counts[url])
+		}
+	}
+	if len(counts) != len(urls) {
+		t.Errorf("%s: hay %d urls, se esperaban %d", what, len(counts), len(urls))
+	}
+}
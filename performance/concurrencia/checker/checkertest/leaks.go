@@ -0,0 +1,55 @@
+package checkertest
+
+import (
+	"bytes"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// CheckLeaks hace fallar el test si al terminar quedan gorutinas que no existían cuando se llamó. una gorutina
+// bloqueada para siempre en un canal que nadie lee no da ningún error, solo ocupa memoria hasta que el proceso
+// termina, y en un servidor que verifica urls todo el día se va acumulando. se espera un poco antes de fallar
+// porque las gorutinas que ya terminaron su trabajo pueden tardar en salir
+func CheckLeaks(t testing.TB) {
+	t.Helper()
+	before := goroutines()
+	t.Cleanup(func() {
+		var leaked []string
+		for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			leaked = leaked[:0]
+			for id, stack := range goroutines() {
+				if _, ok := before[id]; !ok {
+					leaked = append(leaked, stack)
+				}
+			}
+			if len(leaked) == 0 {
+				return
+			}
+		}
+		t.Errorf("quedaron %d gorutinas sin terminar:\n\n%s", len(leaked), strings.Join(leaked, "\n\n"))
+	})
+}
+
+// goroutines devuelve el stack de cada gorutina viva por su id, sin la que llama
+func goroutines() map[string]string {
+	buf := make([]byte, 1<<20)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+
+	stacks := map[string]string{}
+	// el primer stack es el de la gorutina que llama
+	for _, stack := range bytes.Split(buf, []byte("\n\n"))[1:] {
+		// cada stack empieza con "goroutine 18 [chan receive]:"
+		header, _, _ := strings.Cut(string(stack), " [")
+		stacks[strings.TrimPrefix(header, "goroutine ")] = string(stack)
+	}
+	return stacks
+}
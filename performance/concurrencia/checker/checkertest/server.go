@@ -0,0 +1,64 @@
+// el paquete checkertest tiene utilidades para probar y hacer benchmarks de los ejemplos
+// contra un servidor local, sin depender de sitios externos que cambian con el tiempo
+package checkertest
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"arqsoft/tacticas-arq-go/performance/concurrencia/checker"
+)
+
+// LatencyProfile devuelve cuánto demora en responder el pedido número n que recibe el servidor
+type LatencyProfile func(n int64) time.Duration
+
+// Constant demora siempre lo mismo
+func Constant(d time.Duration) LatencyProfile {
+	return func(int64) time.Duration { return d }
+}
+
+// TailLatency demora fast salvo uno de cada every pedidos que demora slow, es el caso en que el
+// tiempo total lo define un único pedido lento
+func TailLatency(fast, slow time.Duration, every int64) LatencyProfile {
+	return func(n int64) time.Duration {
+		if every > 0 && n%every == 0 {
+			return slow
+		}
+		return fast
+	}
+}
+
+// NewServer levanta un servidor HTTP local que responde 200 a cada pedido luego de la demora del perfil.
+// si el cliente cancela el pedido el servidor deja de esperar
+func NewServer(profile LatencyProfile) *httptest.Server {
+	var requests int64
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt64(&requests, 1)
+		select {
+		case <-time.After(profile(n)):
+		case <-r.Context().Done():
+		}
+	}))
+}
+
+// URLs devuelve n urls distintos del servidor
+func URLs(server *httptest.Server, n int) []string {
+	urls := make([]string, n)
+	for i := range urls {
+		urls[i] = fmt.Sprintf("%s/site/%d", server.URL, i)
+	}
+	return urls
+}
+
+// ReportPercentiles agrega al benchmark los percentiles 50 y 99 de las duraciones medidas en cada iteración
+func ReportPercentiles(b *testing.B, durations []time.Duration) {
+	sorted := append([]time.Duration(nil), durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	b.ReportMetric(float64(checker.Percentile(sorted, 50).Nanoseconds()), "p50-ns")
+	b.ReportMetric(float64(checker.Percentile(sorted, 99).Nanoseconds()), "p99-ns")
+}
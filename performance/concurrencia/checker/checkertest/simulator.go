@@ -0,0 +1,492 @@
+package checkertest
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/*
+	simulador de internet: muchos hosts virtuales servidos en localhost.
+
+	los ejemplos verifican dominios reales que cambian con el tiempo (arqsoft.com puede existir o no), así que
+	dos ejecuciones del mismo ejemplo no dan lo mismo y no se pueden comparar. el Simulator sirve en un puerto
+	http y uno https todos los hosts de un escenario, cada uno con su comportamiento:
+		- status, headers y body de la respuesta
+		- latency: demora antes de responder
+		- body_size y body_rate: un body largo que se escribe de a poco (bytes por segundo), solo con GET
+		- reset: corta la conexión con un RST en lugar de responder
+		- tls: "self-signed" o "expired", el host se sirve por https con un certificado autofirmado o vencido
+		- flap: fases que se repiten en ciclo, cada una con su respuesta y que dura una cantidad de pedidos
+		  (requests) o un tiempo (duration). con requests el resultado no depende de la velocidad de la máquina
+
+		{
+		  "http": "127.0.0.1:8080",
+		  "https": "127.0.0.1:8443",
+		  "hosts": {
+		    "ort.edu.uy": {"status": 200},
+		    "github.com": {"latency": "300ms"},
+		    "arqsoft.com": {"flap": [{"requests": 2, "status": 200}, {"requests": 1, "status": 503}]},
+		    "instagram.com": {"tls": "self-signed"},
+		    "gitlab.com": {"reset": true}
+		  }
+		}
+
+	los hosts se eligen por el header Host, como los hosts virtuales de cualquier servidor web. para que los
+	ejemplos lleguen al simulador por el nombre, Hosts devuelve las IPs fijas (el -resolve o -hosts-file de los
+	ejemplos, ver checker.NetworkConfig) y URLs los urls de cada host con el puerto. cmd/simulator es el comando
+	que levanta un escenario y escribe esos dos archivos.
+*/
+
+// Response es lo que contesta un host virtual a cada pedido
+type Response struct {
+	// Status de la respuesta, 0 para 200
+	Status  int
+	Latency time.Duration
+	Headers map[string]string
+	Body    string
+	// BodySize agrega al final de Body esa cantidad de bytes de relleno
+	BodySize int
+	// BodyRate son los bytes por segundo con los que se escribe el body, 0 sin límite
+	BodyRate int
+	// Reset corta la conexión sin responder
+	Reset bool
+}
+
+type rawResponse struct {
+	Status   int               `json:"status"`
+	Latency  string            `json:"latency"`
+	Headers  map[string]string `json:"headers"`
+	Body     string            `json:"body"`
+	BodySize int               `json:"body_size"`
+	BodyRate int               `json:"body_rate"`
+	Reset    bool              `json:"reset"`
+}
+
+func (r *Response) UnmarshalJSON(data []byte) error {
+	var raw rawResponse
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*r = Response{Status: raw.Status, Headers: raw.Headers, Body: raw.Body, BodySize: raw.BodySize, BodyRate: raw.BodyRate, Reset: raw.Reset}
+	if raw.Latency != "" {
+		var err error
+		if r.Latency, err = time.ParseDuration(raw.Latency); err != nil {
+			return fmt.Errorf("latency inválido: %w", err)
+		}
+	}
+	if r.Status != 0 && (r.Status < 100 || r.Status > 999) {
+		return fmt.Errorf("status inválido: %d", r.Status)
+	}
+	if r.BodySize < 0 || r.BodyRate < 0 {
+		return errors.New("body_size y body_rate no pueden ser negativos")
+	}
+	return nil
+}
+
+// Phase es una fase del ciclo de un host que flapea: su respuesta dura Requests pedidos o Duration
+type Phase struct {
+	Response Response
+	Requests int
+	Duration time.Duration
+}
+
+func (p *Phase) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Requests int    `json:"requests"`
+		Duration string `json:"duration"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	// la respuesta de la fase va en el mismo objeto que requests y duration
+	if err := json.Unmarshal(data, &p.Response); err != nil {
+		return err
+	}
+	p.Requests = raw.Requests
+	if raw.Duration != "" {
+		var err error
+		if p.Duration, err = time.ParseDuration(raw.Duration); err != nil {
+			return fmt.Errorf("duration inválido: %w", err)
+		}
+	}
+	if (p.Requests > 0) == (p.Duration > 0) {
+		return errors.New("cada fase tiene que durar requests o duration")
+	}
+	return nil
+}
+
+// certificados que puede tener un host servido por https
+const (
+	SelfSigned = "self-signed"
+	Expired    = "expired"
+)
+
+// VirtualHost es la configuración de un host del escenario: una respuesta fija o un ciclo de fases
+type VirtualHost struct {
+	Response Response
+	// TLS es "" para servirlo por http, SelfSigned o Expired para servirlo por https
+	TLS  string
+	Flap []Phase
+}
+
+func (h *VirtualHost) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		TLS  string  `json:"tls"`
+		Flap []Phase `json:"flap"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &h.Response); err != nil {
+		return err
+	}
+	switch raw.TLS {
+	case "", SelfSigned, Expired:
+	default:
+		return fmt.Errorf("tls debe ser %q o %q y es %q", SelfSigned, Expired, raw.TLS)
+	}
+	h.TLS, h.Flap = raw.TLS, raw.Flap
+	// las fases se cuentan todas por pedidos o todas por tiempo, mezcladas el ciclo no tiene largo
+	for _, phase := range h.Flap[min(1, len(h.Flap)):] {
+		if (phase.Requests > 0) != (h.Flap[0].Requests > 0) {
+			return errors.New("las fases de flap tienen que durar todas requests o todas duration")
+		}
+	}
+	return nil
+}
+
+// Scenario es el escenario del simulador: las direcciones donde escucha y los hosts virtuales por nombre
+type Scenario struct {
+	// HTTP y HTTPS son las direcciones host:puerto donde escucha, con puerto 0 se elige uno libre
+	HTTP  string                 `json:"http"`
+	HTTPS string                 `json:"https"`
+	Hosts map[string]VirtualHost `json:"hosts"`
+}
+
+// direcciones por defecto del escenario, fijas para que los urls sean los mismos en cada ejecución
+const (
+	DefaultHTTPAddr  = "127.0.0.1:8080"
+	DefaultHTTPSAddr = "127.0.0.1:8443"
+)
+
+// LoadScenario lee un escenario de un archivo JSON
+func LoadScenario(path string) (Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Scenario{}, err
+	}
+	var scenario Scenario
+	if err := json.Unmarshal(data, &scenario); err != nil {
+		return Scenario{}, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(scenario.Hosts) == 0 {
+		return Scenario{}, fmt.Errorf("%s: el escenario no tiene hosts", path)
+	}
+	return scenario, nil
+}
+
+// Simulator sirve los hosts virtuales de un escenario
+type Simulator struct {
+	scenario Scenario
+	start    time.Time
+	hosts    map[string]*virtualHost
+
+	httpServer, httpsServer *http.Server
+	httpAddr, httpsAddr     net.Addr
+	wg                      sync.WaitGroup
+}
+
+// virtualHost es un host del escenario con la cantidad de pedidos que recibió y su certificado
+type virtualHost struct {
+	VirtualHost
+	requests atomic.Int64
+	cert     *tls.Certificate
+}
+
+// NewSimulator empieza a escuchar en las direcciones del escenario y a servir sus hosts
+func NewSimulator(scenario Scenario) (*Simulator, error) {
+	s := &Simulator{scenario: scenario, start: time.Now(), hosts: make(map[string]*virtualHost, len(scenario.Hosts))}
+	for name, cfg := range scenario.Hosts {
+		vh := &virtualHost{VirtualHost: cfg}
+		if cfg.TLS != "" {
+			cert, err := selfSignedCert(name, cfg.TLS == Expired)
+			if err != nil {
+				return nil, err
+			}
+			vh.cert = cert
+		}
+		s.hosts[strings.ToLower(name)] = vh
+	}
+
+	httpAddr, httpsAddr := scenario.HTTP, scenario.HTTPS
+	if httpAddr == "" {
+		httpAddr = DefaultHTTPAddr
+	}
+	if httpsAddr == "" {
+		httpsAddr = DefaultHTTPSAddr
+	}
+	httpListener, err := net.Listen("tcp", httpAddr)
+	if err != nil {
+		return nil, err
+	}
+	tcpListener, err := net.Listen("tcp", httpsAddr)
+	if err != nil {
+		httpListener.Close()
+		return nil, err
+	}
+	// solo HTTP/1.1: el reset necesita tomar la conexión (Hijack), que HTTP/2 no permite
+	httpsListener := tls.NewListener(tcpListener, &tls.Config{GetCertificate: s.certificate, NextProtos: []string{"http/1.1"}})
+	s.httpAddr, s.httpsAddr = httpListener.Addr(), tcpListener.Addr()
+
+	// los resets y los handshakes que el cliente rechaza son parte del escenario, no hace falta registrarlos
+	errorLog := log.New(io.Discard, "", 0)
+	s.httpServer = &http.Server{Handler: s, ErrorLog: errorLog}
+	s.httpsServer = &http.Server{Handler: s, ErrorLog: errorLog, TLSNextProto: map[string]func(*http.Server, *tls.Conn, http.Handler){}}
+	for server, listener := range map[*http.Server]net.Listener{s.httpServer: httpListener, s.httpsServer: httpsListener} {
+		s.wg.Add(1)
+		go func(server *http.Server, listener net.Listener) {
+			defer s.wg.Done()
+			server.Serve(listener)
+		}(server, listener)
+	}
+	return s, nil
+}
+
+// Close deja de escuchar y corta las conexiones abiertas
+func (s *Simulator) Close() error {
+	err := errors.Join(s.httpServer.Close(), s.httpsServer.Close())
+	s.wg.Wait()
+	return err
+}
+
+// URL devuelve el url de un host del escenario, con el esquema y el puerto en que se sirve
+func (s *Simulator) URL(name string) string {
+	vh, ok := s.hosts[strings.ToLower(name)]
+	if !ok {
+		return ""
+	}
+	if vh.TLS != "" {
+		return fmt.Sprintf("https://%s:%d", name, s.httpsAddr.(*net.TCPAddr).Port)
+	}
+	return fmt.Sprintf("http://%s:%d", name, s.httpAddr.(*net.TCPAddr).Port)
+}
+
+// URLs devuelve los urls de todos los hosts ordenados por nombre
+func (s *Simulator) URLs() []string {
+	names := make([]string, 0, len(s.hosts))
+	for name := range s.hosts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	urls := make([]string, len(names))
+	for i, name := range names {
+		urls[i] = s.URL(name)
+	}
+	return urls
+}
+
+// Hosts devuelve la IP de cada host del escenario, para checker.NetworkConfig.Hosts
+func (s *Simulator) Hosts() map[string]string {
+	ip := s.httpAddr.(*net.TCPAddr).IP.String()
+	hosts := make(map[string]string, len(s.hosts))
+	for name := range s.hosts {
+		hosts[name] = ip
+	}
+	return hosts
+}
+
+// WriteHostsFile escribe los hosts con el formato de /etc/hosts que lee -hosts-file
+func (s *Simulator) WriteHostsFile(w io.Writer) error {
+	hosts := s.Hosts()
+	names := make([]string, 0, len(hosts))
+	for name := range hosts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, err := fmt.Fprintf(w, "%s %s\n", hosts[name], name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Simulator) certificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	vh, ok := s.hosts[strings.ToLower(hello.ServerName)]
+	if !ok || vh.cert == nil {
+		return nil, fmt.Errorf("el host %q no se sirve por https", hello.ServerName)
+	}
+	return vh.cert, nil
+}
+
+func (s *Simulator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name, _, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		name = r.Host
+	}
+	vh, ok := s.hosts[strings.ToLower(name)]
+	if !ok {
+		http.Error(w, fmt.Sprintf("el host %s no está en el escenario", name), http.StatusNotFound)
+		return
+	}
+	if (r.TLS != nil) != (vh.TLS != "") {
+		http.Error(w, fmt.Sprintf("el host %s no se sirve por este puerto", name), http.StatusMisdirectedRequest)
+		return
+	}
+
+	resp := vh.response(vh.requests.Add(1), time.Since(s.start))
+	select {
+	case <-time.After(resp.Latency):
+	case <-r.Context().Done():
+		return
+	}
+	if resp.Reset {
+		reset(w)
+		return
+	}
+
+	for key, value := range resp.Headers {
+		w.Header().Set(key, value)
+	}
+	status := resp.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	if r.Method != http.MethodHead {
+		w.Header().Set("Content-Length", fmt.Sprint(len(resp.Body)+resp.BodySize))
+	}
+	w.WriteHeader(status)
+	if r.Method != http.MethodHead {
+		writeBody(r.Context(), w, resp)
+	}
+}
+
+// response devuelve la respuesta del pedido número n, que llegó elapsed después de iniciado el simulador
+func (h *virtualHost) response(n int64, elapsed time.Duration) Response {
+	if len(h.Flap) == 0 {
+		return h.Response
+	}
+	// la posición en el ciclo se cuenta en pedidos o en tiempo, según cómo duran las fases
+	var cycle, position int64
+	for _, phase := range h.Flap {
+		cycle += int64(phase.Requests) + int64(phase.Duration)
+	}
+	if h.Flap[0].Requests > 0 {
+		position = (n - 1) % cycle
+	} else {
+		position = int64(elapsed) % cycle
+	}
+	for _, phase := range h.Flap {
+		if position -= int64(phase.Requests) + int64(phase.Duration); position < 0 {
+			return phase.Response
+		}
+	}
+	return h.Flap[len(h.Flap)-1].Response
+}
+
+// writeBody escribe el body y el relleno, de a body_rate bytes por segundo si se indicó
+func writeBody(ctx context.Context, w http.ResponseWriter, resp Response) {
+	body := io.MultiReader(strings.NewReader(resp.Body), io.LimitReader(padding{}, int64(resp.BodySize)))
+	if resp.BodyRate == 0 {
+		io.Copy(w, body)
+		return
+	}
+
+	// se escribe en trozos cada 100ms y se hace flush para que el cliente los reciba de a poco
+	chunk := int64(max(1, resp.BodyRate/10))
+	flusher, _ := w.(http.Flusher)
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		n, err := io.CopyN(w, body, chunk)
+		if flusher != nil {
+			flusher.Flush()
+		}
+		if n < chunk || err != nil {
+			return
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// padding es un reader infinito de 'x'
+type padding struct{}
+
+func (padding) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 'x'
+	}
+	return len(p), nil
+}
+
+// reset corta la conexión con un RST: con SO_LINGER en 0 el close descarta lo pendiente y no hace el cierre
+// ordenado de TCP, el cliente recibe "connection reset by peer"
+func reset(w http.ResponseWriter) {
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return
+	}
+	conn, _, err := hijacker.Hijack()
+	if err != nil {
+		return
+	}
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		// se corta la conexión TCP de abajo, cerrar la de TLS primero manda un aviso de cierre
+		conn = tlsConn.NetConn()
+	}
+	if tcpConn, ok := conn.(*net.TCPConn); ok {
+		tcpConn.SetLinger(0)
+	}
+	conn.Close()
+}
+
+// selfSignedCert genera un certificado autofirmado para el nombre, vencido ayer si expired
+func selfSignedCert(name string, expired bool) (*tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	notBefore, notAfter := time.Now().Add(-time.Hour), time.Now().Add(365*24*time.Hour)
+	if expired {
+		notBefore, notAfter = time.Now().Add(-48*time.Hour), time.Now().Add(-24*time.Hour)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name, Organization: []string{"simulador tacticas-arq-go"}},
+		DNSNames:              []string{name},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
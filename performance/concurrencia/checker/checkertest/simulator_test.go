@@ -0,0 +1,165 @@
+package checkertest
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"arqsoft/tacticas-arq-go/performance/concurrencia/checker"
+)
+
+// newSimulator levanta el escenario en puertos libres y devuelve el cliente que resuelve sus hosts
+func newSimulator(t *testing.T, hosts string) (*Simulator, *http.Client) {
+	t.Helper()
+	var scenario Scenario
+	if err := json.Unmarshal([]byte(`{"http": "127.0.0.1:0", "https": "127.0.0.1:0", "hosts": `+hosts+`}`), &scenario); err != nil {
+		t.Fatal(err)
+	}
+	sim, err := NewSimulator(scenario)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sim.Close() })
+
+	client, err := checker.NewClient(checker.DefaultTransportConfig(), checker.NetworkConfig{Hosts: sim.Hosts()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sim, client
+}
+
+// cada escenario produce siempre la misma categoría de resultado
+func TestSimulatorScenarios(t *testing.T) {
+	sim, client := newSimulator(t, `{
+		"ok.test": {},
+		"caido.test": {"status": 503},
+		"lento.test": {"latency": "1s"},
+		"reset.test": {"reset": true},
+		"autofirmado.test": {"tls": "self-signed"},
+		"vencido.test": {"tls": "expired"}
+	}`)
+	prober := checker.NewHeadProber(client)
+
+	for name, want := range map[string]checker.ErrorKind{
+		"ok.test":          checker.NoError,
+		"caido.test":       checker.NoError,
+		"lento.test":       checker.Timeout,
+		"reset.test":       checker.ProtocolError,
+		"autofirmado.test": checker.TLSError,
+		"vencido.test":     checker.TLSError,
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+			defer cancel()
+			res := prober.Probe(ctx, sim.URL(name))
+			if res.Kind() != want {
+				t.Fatalf("categoría = %s, se esperaba %s (error: %v)", res.Kind(), want, res.Err)
+			}
+			if want == checker.NoError && res.OK() != (name == "ok.test") {
+				t.Errorf("status %d inesperado", res.StatusCode)
+			}
+		})
+	}
+}
+
+// un host que flapea por cantidad de pedidos repite el ciclo
+func TestSimulatorFlapsByRequests(t *testing.T) {
+	sim, client := newSimulator(t, `{"flap.test": {"flap": [{"requests": 2}, {"requests": 1, "status": 503}]}}`)
+	prober := checker.NewHeadProber(client)
+
+	var got []int
+	for i := 0; i < 6; i++ {
+		got = append(got, prober.Probe(context.Background(), sim.URL("flap.test")).StatusCode)
+	}
+	want := []int{200, 200, 503, 200, 200, 503}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("status = %v, se esperaba %v", got, want)
+		}
+	}
+}
+
+// el body lento se escribe de a poco respetando body_rate
+func TestSimulatorSlowBody(t *testing.T) {
+	sim, client := newSimulator(t, `{"lento.test": {"body": "hola", "body_size": 1996, "body_rate": 5000}}`)
+
+	start := time.Now()
+	resp, err := client.Get(sim.URL("lento.test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(body) != 2000 || !strings.HasPrefix(string(body), "hola") {
+		t.Errorf("body de %d bytes: %.20q", len(body), body)
+	}
+	// 2000 bytes a 5000 por segundo son 4 trozos de 500 bytes, con 100ms entre trozos
+	if elapsed := time.Since(start); elapsed < 300*time.Millisecond {
+		t.Errorf("el body llegó en %v, demasiado rápido para body_rate", elapsed)
+	}
+}
+
+func TestSimulatorRejectsOtherHosts(t *testing.T) {
+	sim, client := newSimulator(t, `{"ok.test": {}, "seguro.test": {"tls": "self-signed"}}`)
+
+	// seguro.test se sirve por https, en el puerto http contesta 421
+	url := strings.Replace(sim.URL("ok.test"), "ok.test", "seguro.test", 1)
+	resp, err := client.Head(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMisdirectedRequest {
+		t.Errorf("status = %d, se esperaba 421", resp.StatusCode)
+	}
+}
+
+func TestLoadScenario(t *testing.T) {
+	write := func(content string) string {
+		path := filepath.Join(t.TempDir(), "escenario.json")
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	scenario, err := LoadScenario(write(`{"hosts": {"a.test": {"latency": "50ms", "flap": [{"duration": "1s"}, {"duration": "2s", "reset": true}]}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	host := scenario.Hosts["a.test"]
+	if host.Response.Latency != 50*time.Millisecond || len(host.Flap) != 2 || !host.Flap[1].Response.Reset || host.Flap[1].Duration != 2*time.Second {
+		t.Errorf("escenario leído %+v", host)
+	}
+
+	for name, tc := range map[string]struct{ content, want string }{
+		"sin hosts": {`{"hosts": {}}`, "no tiene hosts"},
+		"tls":       {`{"hosts": {"a.test": {"tls": "valido"}}}`, "tls debe ser"},
+		"fase":      {`{"hosts": {"a.test": {"flap": [{"status": 200}]}}}`, "requests o duration"},
+		"mezcla":    {`{"hosts": {"a.test": {"flap": [{"requests": 1}, {"duration": "1s"}]}}}`, "todas requests o todas duration"},
+		"status":    {`{"hosts": {"a.test": {"status": 7}}}`, "status inválido"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := LoadScenario(write(tc.content))
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("se esperaba un error con %q y se obtuvo %v", tc.want, err)
+			}
+		})
+	}
+}
+
+// el escenario de ejemplo del comando tiene que poder leerse
+func TestExampleScenario(t *testing.T) {
+	if _, err := LoadScenario("../cmd/simulator/escenario.json"); err != nil {
+		t.Fatal(err)
+	}
+}
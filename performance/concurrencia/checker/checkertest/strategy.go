@@ -0,0 +1,107 @@
+package checkertest
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"arqsoft/tacticas-arq-go/performance/concurrencia/checker"
+)
+
+// Strategy es un ejemplo de estrategia de concurrencia visto desde las pruebas: Use reemplaza el logger y el
+// prober del ejemplo (las variables de su paquete main) y devuelve cómo dejar los originales, y Check es la
+// función que verifica la lista de urls
+type Strategy struct {
+	Use   func(logger *slog.Logger, prober checker.Prober) (restore func())
+	Check func(ctx context.Context, urls []string)
+	// DropsOnCancel indica que al cancelar la estrategia deja de leer los resultados (como el sink de
+	// pipes-filters_v2), así que los urls que no llegaron no se registran en lugar de registrarse como cancelados
+	DropsOnCancel bool
+}
+
+// UseFakes le pasa a la estrategia un FakeProber que demora delay con los resultados de FakeSites(n) y un
+// logger de NewLogs, hasta que termina el test. devuelve los urls, el prober y los registros
+func (s Strategy) UseFakes(t testing.TB, delay time.Duration, n int) ([]string, *FakeProber, *Logs) {
+	t.Helper()
+	urls, results := FakeSites(n)
+	fake := &FakeProber{Delay: delay, Results: results}
+	logger, logs := NewLogs()
+	t.Cleanup(s.Use(logger, fake))
+	return urls, fake, logs
+}
+
+// RunStrategyTests ejecuta las pruebas de correctitud que comparten todas las estrategias, con el detector de
+// carreras (go test -race) además revisan que no haya accesos concurrentes sin sincronizar:
+//   - cada url se verifica y se registra una sola vez y el resumen los cuenta a todos
+//   - al cancelar termina enseguida y los urls que faltan se reportan como cancelados (o no se registran,
+//     ver DropsOnCancel), sin registrar dos veces un url
+//   - en los dos casos no quedan gorutinas vivas al terminar (ver CheckLeaks)
+//   - la contraseña de un url con credenciales no aparece en ningún registro, tampoco en los de debug ni en
+//     los de las entradas repetidas o inválidas
+func RunStrategyTests(t *testing.T, s Strategy) {
+	t.Run("ReportsEachURLOnce", func(t *testing.T) {
+		CheckLeaks(t)
+		urls, fake, logs := s.UseFakes(t, time.Millisecond, 50)
+
+		s.Check(context.Background(), urls)
+
+		ExpectOnce(t, "verificados", urls, fake.Calls())
+		ExpectOnce(t, "registrados", urls, logs.ByURL())
+		summary := logs.Find("resumen")
+		// de 50 urls hay 10 múltiplos de 5 (503) y 6 múltiplos de 7 que no lo son de 5 (timeout)
+		if summary["total"] != float64(50) || summary["ok"] != float64(34) || summary["false"] != float64(10) {
+			t.Errorf("resumen inesperado %v", summary)
+		}
+	})
+
+	t.Run("Cancel", func(t *testing.T) {
+		CheckLeaks(t)
+		urls, _, logs := s.UseFakes(t, time.Second, 50)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(20*time.Millisecond, cancel)
+		start := time.Now()
+		s.Check(ctx, urls)
+		if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+			t.Errorf("terminó %v después de cancelar", elapsed)
+		}
+
+		if s.DropsOnCancel {
+			for url, n := range logs.ByURL() {
+				if n > 1 {
+					t.Errorf("%s se registró %d veces", url, n)
+				}
+			}
+			return
+		}
+		ExpectOnce(t, "registrados", urls, logs.ByURL())
+		if summary := logs.Find("resumen"); summary["total"] != float64(50) {
+			t.Errorf("resumen inesperado %v", summary)
+		}
+	})
+
+	t.Run("RedactsPasswords", func(t *testing.T) {
+		const password = "s3cr3tpw"
+		urls := []string{
+			"http://admin:" + password + "@sitio1.test",
+			"http://admin:" + password + "@sitio2.test",
+			"http://admin:" + password + "@sitio1.test",
+			"http://admin:" + password + "@sitio 3.test",
+		}
+		fake := &FakeProber{Results: map[string]checker.Result{urls[1]: {StatusCode: 503}}}
+		logs := &Logs{}
+		logger := slog.New(slog.NewJSONHandler(logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+		t.Cleanup(s.Use(logger, fake))
+
+		s.Check(context.Background(), urls)
+
+		if logs.Find("resumen") == nil {
+			t.Fatal("no se registró el resumen")
+		}
+		if strings.Contains(logs.String(), password) {
+			t.Errorf("la contraseña aparece en los registros:\n%s", logs.String())
+		}
+	})
+}
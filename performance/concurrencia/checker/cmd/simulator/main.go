@@ -0,0 +1,96 @@
+/*
+	simulador de internet para los ejemplos, ver checkertest.Simulator.
+
+	levanta los hosts virtuales de un escenario en localhost y escribe el archivo de hosts y el inventario con
+	los que los ejemplos llegan a ellos por el nombre. así una clase o un benchmark da el mismo resultado en cada
+	ejecución, sin depender de sitios reales. desde la carpeta checker:
+
+		go run ./cmd/simulator -scenario=cmd/simulator/escenario.json -hosts-file=/tmp/sim.hosts -inventory=/tmp/sim.txt
+
+	y en otra terminal, desde la carpeta de cualquier ejemplo:
+
+		go run . -hosts-file=/tmp/sim.hosts -inventory=/tmp/sim.txt
+
+	el simulador sirve hasta que se lo interrumpe con ctrl-c.
+*/
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"arqsoft/tacticas-arq-go/performance/concurrencia/checker"
+	"arqsoft/tacticas-arq-go/performance/concurrencia/checker/checkertest"
+)
+
+func main() {
+	scenarioPath := flag.String("scenario", "escenario.json", "archivo JSON con el escenario")
+	hostsFile := flag.String("hosts-file", "", "archivo donde escribir los hosts con el formato de /etc/hosts, para -hosts-file de los ejemplos")
+	inventoryFile := flag.String("inventory", "", "archivo donde escribir los urls de los hosts, para -inventory de los ejemplos")
+	logConfig := checker.RegisterLogFlags(flag.CommandLine)
+	flag.Parse()
+
+	logger, err := logConfig.Logger(os.Stderr)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	scenario, err := checkertest.LoadScenario(*scenarioPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	sim, err := checkertest.NewSimulator(scenario)
+	if err != nil {
+		logger.Error("no se pudo levantar el simulador", checker.KeyError, err)
+		os.Exit(1)
+	}
+	defer sim.Close()
+
+	if *hostsFile != "" {
+		if err := writeFile(*hostsFile, sim.WriteHostsFile); err != nil {
+			logger.Error("no se pudo escribir el archivo de hosts", checker.KeyError, err)
+			os.Exit(1)
+		}
+	}
+	if *inventoryFile != "" {
+		err := writeFile(*inventoryFile, func(w io.Writer) error {
+			_, err := fmt.Fprintln(w, strings.Join(sim.URLs(), "\n"))
+			return err
+		})
+		if err != nil {
+			logger.Error("no se pudo escribir el inventario", checker.KeyError, err)
+			os.Exit(1)
+		}
+	}
+
+	for _, url := range sim.URLs() {
+		logger.Info("host simulado", checker.KeyURL, url)
+	}
+	logger.Info("simulador listo, ctrl-c para terminar", "hosts_file", *hostsFile, "inventory", *inventoryFile)
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	<-stop
+	logger.Info("fin del simulador")
+}
+
+// writeFile crea el archivo y escribe su contenido con write
+func writeFile(path string, write func(io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,331 @@
+package checker
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ProbeConfig es la configuración de las tácticas que se aplican alrededor del HEAD, la toman todos
+// los ejemplos de los mismos flags para que las estrategias se puedan comparar en igualdad de condiciones
+type ProbeConfig struct {
+	Limits    LimitConfig
+	Breaker   BreakerConfig
+	Hedge     HedgeConfig
+	Cache     CacheConfig
+	Expect    ExpectConfig
+	Request   RequestFlags
+	Network   NetworkConfig
+	Transport TransportConfig
+	// archivo con formato de /etc/hosts que se agrega a Network.Hosts
+	HostsFile string
+	// tiempo máximo de cada verificación, 0 sin límite (ver ProbeTimeout)
+	Timeout time.Duration
+	// ventana de advertencia de vencimiento de los certificados TLS
+	CertWarnWindow time.Duration
+	// política de redirecciones
+	Redirects RedirectPolicy
+	// archivo con las autoridades de los servidores grpcs://
+	GRPCRootCAs string
+	// respetar el robots.txt de los sitios, ver Robots
+	Robots bool
+
+	// la cache que se creó en Prober, para guardarla en Close
+	cache *Cache
+	// las demás tácticas que se armaron en Prober, para envolver otros probers con el mismo estado (ver Wrap)
+	tactics []Middleware
+	// la configuración de los pedidos que se creó en Prober, para que la use también el crawler
+	requests *RequestConfigs
+	client   *http.Client
+}
+
+// RegisterProbeFlags agrega al FlagSet los flags de las tácticas y devuelve la configuración
+// que queda cargada luego de fs.Parse
+func RegisterProbeFlags(fs *flag.FlagSet) *ProbeConfig {
+	cfg := &ProbeConfig{}
+	fs.Float64Var(&cfg.Limits.Rate, "rate", 0, "pedidos por segundo entre todos los hosts (0 sin límite)")
+	fs.IntVar(&cfg.Limits.Burst, "burst", 1, "ráfaga máxima de pedidos entre todos los hosts")
+	fs.Float64Var(&cfg.Limits.HostRate, "host-rate", 0, "pedidos por segundo a un mismo host (0 sin límite)")
+	fs.IntVar(&cfg.Limits.HostBurst, "host-burst", 1, "ráfaga máxima de pedidos a un mismo host")
+	fs.IntVar(&cfg.Limits.MaxConnsPerHost, "host-max-conns", 0, "máximo de pedidos concurrentes a un mismo host (0 sin límite)")
+
+	fs.IntVar(&cfg.Breaker.FailureThreshold, "breaker-failures", 0, "fallas consecutivas de un host para abrir su circuito (0 sin circuit breaker)")
+	fs.DurationVar(&cfg.Breaker.CoolDown, "breaker-cooldown", 30*time.Second, "tiempo que el circuito de un host queda abierto")
+	fs.IntVar(&cfg.Breaker.HalfOpenProbes, "breaker-half-open", 1, "verificaciones de prueba permitidas con el circuito half-open")
+
+	fs.Float64Var(&cfg.Hedge.Percentile, "hedge-percentile", 0, "percentil de latencia a partir del cual se lanza un segundo HEAD (0 sin hedging)")
+	fs.DurationVar(&cfg.Hedge.Delay, "hedge-delay", 200*time.Millisecond, "demora del segundo HEAD hasta tener latencias observadas")
+	fs.IntVar(&cfg.Hedge.Window, "hedge-window", 100, "cantidad de latencias recientes para calcular el percentil")
+
+	fs.DurationVar(&cfg.Cache.TTL, "cache-ttl", 0, "tiempo que se reutiliza el resultado de un url (0 sin cache)")
+	fs.StringVar(&cfg.Cache.Path, "cache-file", "", "archivo donde persistir la cache entre ejecuciones")
+
+	fs.StringVar(&cfg.Expect.Status, "expect-status", "200", "status aceptados como éxito, por ejemplo 200-299,301,401")
+	fs.DurationVar(&cfg.Expect.MaxResponseTime, "max-response-time", 0, "tiempo máximo de respuesta para considerarla un éxito (0 sin máximo)")
+	fs.StringVar(&cfg.Expect.Path, "expect", "", "archivo JSON con los criterios de éxito por url")
+	fs.BoolVar(&cfg.Expect.GET, "get", false, "verificar con GET en lugar de HEAD")
+	fs.StringVar(&cfg.Expect.BodyContains, "body-contains", "", "texto que tiene que contener el body (implica -get)")
+	fs.Int64Var(&cfg.Expect.MaxBodyBytes, "max-body-bytes", DefaultMaxBodyBytes, "máximo de bytes del body que se leen con GET")
+
+	fs.IntVar(&cfg.Redirects.Max, "max-redirects", defaultMaxRedirects, "máximo de redirecciones que se siguen")
+	fs.BoolVar(&cfg.Redirects.None, "no-redirects", false, "no seguir las redirecciones, el 3xx es la respuesta")
+	fs.BoolVar(&cfg.Redirects.FailCrossDomain, "fail-cross-domain", false, "fallar si una redirección lleva a otro dominio")
+	fs.BoolVar(&cfg.Redirects.FailDowngrade, "fail-downgrade", false, "fallar si una redirección pasa de https a http")
+
+	fs.StringVar(&cfg.Request.UserAgent, "user-agent", UserAgent, "User-Agent de los pedidos")
+	fs.Func("header", "header de los pedidos como \"Nombre: valor\", se puede repetir. el valor puede ser env:VARIABLE o file:camino", func(v string) error {
+		if _, _, ok := strings.Cut(v, ":"); !ok {
+			return fmt.Errorf("%q no tiene la forma Nombre: valor", v)
+		}
+		cfg.Request.Headers = append(cfg.Request.Headers, v)
+		return nil
+	})
+	fs.StringVar(&cfg.Request.Bearer, "bearer", "", "token bearer de los pedidos, como env:VARIABLE o file:camino")
+	fs.StringVar(&cfg.Request.Path, "request-config", "", "archivo JSON con headers, autenticación y cookies por host o por url")
+
+	fs.StringVar(&cfg.Network.Proxy, "proxy", "", "proxy de los pedidos http y https: http://, https:// o socks5://host:puerto (por defecto HTTP_PROXY y HTTPS_PROXY)")
+	fs.StringVar(&cfg.Network.DNSServer, "dns-server", "", "servidor DNS (host:puerto) con el que se resuelven los nombres")
+	fs.Func("resolve", "nombre=ip que se usa en lugar de resolver el nombre, se puede repetir", func(v string) error {
+		name, ip, err := ParseHostOverride(v)
+		if err != nil {
+			return err
+		}
+		if cfg.Network.Hosts == nil {
+			cfg.Network.Hosts = map[string]string{}
+		}
+		cfg.Network.Hosts[name] = ip
+		return nil
+	})
+	fs.StringVar(&cfg.HostsFile, "hosts-file", "", "archivo con formato de /etc/hosts con IPs fijas para los nombres")
+	fs.StringVar(&cfg.Network.LocalAddr, "source-addr", "", "IP local desde la que salen las conexiones")
+
+	def := DefaultTransportConfig()
+	fs.IntVar(&cfg.Transport.MaxIdleConns, "max-idle-conns", def.MaxIdleConns, "máximo de conexiones libres en el pool entre todos los hosts")
+	fs.IntVar(&cfg.Transport.MaxIdleConnsPerHost, "max-idle-conns-per-host", def.MaxIdleConnsPerHost, "máximo de conexiones libres en el pool por host")
+	fs.DurationVar(&cfg.Transport.IdleConnTimeout, "idle-conn-timeout", def.IdleConnTimeout, "tiempo que una conexión libre queda en el pool")
+	fs.BoolVar(&cfg.Transport.DisableKeepAlives, "disable-keep-alives", false, "abrir una conexión nueva para cada pedido")
+	fs.BoolVar(&cfg.Transport.HTTP2, "http2", def.HTTP2, "negociar HTTP/2 con los servidores https")
+	fs.DurationVar(&cfg.Transport.DialTimeout, "dial-timeout", def.DialTimeout, "tiempo máximo para abrir una conexión")
+	fs.DurationVar(&cfg.Transport.TLSHandshakeTimeout, "tls-handshake-timeout", def.TLSHandshakeTimeout, "tiempo máximo del handshake TLS")
+
+	fs.StringVar(&cfg.GRPCRootCAs, "grpc-ca", "", "archivo PEM con las autoridades para verificar los certificados de grpcs://")
+
+	fs.BoolVar(&cfg.Robots, "robots", false, "no verificar los urls que el robots.txt del sitio no permite y respetar su Crawl-delay")
+
+	fs.DurationVar(&cfg.Timeout, "timeout", 10*time.Second, "tiempo máximo de cada verificación, incluidas las redirecciones y el body (0 sin límite)")
+
+	fs.DurationVar(&cfg.CertWarnWindow, "cert-warn-window", 30*24*time.Hour, "advertir de los certificados que vencen dentro de este tiempo")
+	return cfg
+}
+
+// ExpectConfig son los criterios de éxito que se toman de los flags
+type ExpectConfig struct {
+	// criterios por defecto
+	Status          string
+	MaxResponseTime time.Duration
+	GET             bool
+	BodyContains    string
+	MaxBodyBytes    int64
+	// archivo con criterios por url (ver Expectations), su "default" reemplaza a los de los flags
+	Path string
+}
+
+// Expectations arma los criterios de éxito de los flags y del archivo
+func (cfg ExpectConfig) Expectations() (*Expectations, error) {
+	status, err := ParseStatusSet(cfg.Status)
+	if err != nil {
+		return nil, err
+	}
+	def := Expectation{Status: status, MaxDuration: cfg.MaxResponseTime, MaxBodyBytes: cfg.MaxBodyBytes}
+	if cfg.GET {
+		def.Method = http.MethodGet
+	}
+	if cfg.BodyContains != "" {
+		def.BodyContains = []string{cfg.BodyContains}
+	}
+	if cfg.Path == "" {
+		return &Expectations{Default: def}, nil
+	}
+	return LoadExpectations(cfg.Path, def)
+}
+
+// RequestFlags es la configuración de los pedidos que se toma de los flags
+type RequestFlags struct {
+	// configuración por defecto, cada header como "Nombre: valor"
+	UserAgent string
+	Headers   []string
+	Bearer    string
+	// archivo con la configuración por host y por url (ver RequestConfigs), su "default" reemplaza a la de los flags
+	Path string
+}
+
+// Configs arma la configuración de los pedidos de los flags y del archivo
+func (cfg RequestFlags) Configs() (*RequestConfigs, error) {
+	def := RequestConfig{UserAgent: cfg.UserAgent, Bearer: Secret(cfg.Bearer)}
+	for _, header := range cfg.Headers {
+		name, value, _ := strings.Cut(header, ":")
+		if def.Headers == nil {
+			def.Headers = map[string]Secret{}
+		}
+		def.Headers[strings.TrimSpace(name)] = Secret(strings.TrimSpace(value))
+	}
+	if cfg.Path == "" {
+		configs := &RequestConfigs{Default: def}
+		return configs, configs.check()
+	}
+	return LoadRequestConfigs(cfg.Path, def)
+}
+
+// Prober arma el Prober base (HEAD) envuelto con las tácticas configuradas,
+// los eventos de las tácticas (como los cambios de estado de los circuitos) se registran en logger
+func (cfg *ProbeConfig) Prober(logger *slog.Logger) (Prober, error) {
+	expectations, err := cfg.Expect.Expectations()
+	if err != nil {
+		return nil, err
+	}
+	requests, err := cfg.Request.Configs()
+	if err != nil {
+		return nil, err
+	}
+	cfg.requests = requests
+
+	if cfg.HostsFile != "" {
+		if cfg.Network.Hosts == nil {
+			cfg.Network.Hosts = map[string]string{}
+		}
+		if err := LoadHostsFile(cfg.HostsFile, cfg.Network.Hosts); err != nil {
+			return nil, err
+		}
+	}
+	// todos los probers se conectan con la misma configuración de red y los pedidos http comparten un
+	// único transport, así las conexiones de su pool se reutilizan entre verificaciones
+	client, err := NewClient(cfg.Transport, cfg.Network)
+	if err != nil {
+		return nil, err
+	}
+	cfg.client = client
+	dial, err := cfg.Network.DialContext()
+	if err != nil {
+		return nil, err
+	}
+	resolver, err := cfg.Network.Resolver()
+	if err != nil {
+		return nil, err
+	}
+
+	head := NewHeadProber(client)
+	head.Expectations = expectations
+	head.Requests = requests
+	head.CertWarnWindow = cfg.CertWarnWindow
+	head.Redirects = cfg.Redirects
+
+	var middlewares []Middleware
+	cfg.cache, cfg.tactics = nil, nil
+
+	// la cache va primero (la agrega Wrap), un resultado que está en la cache no pasa por ninguna otra táctica
+	if cfg.Cache.TTL > 0 {
+		cache, err := NewCache(cfg.Cache)
+		if err != nil {
+			return nil, err
+		}
+		// los resultados quedan guardados con la evaluación de los criterios, se separan por criterios y pedido
+		cache.Variant = func(url string) string {
+			return Variant(expectations.For(url), requests.For(url))
+		}
+		cfg.cache = cache
+	}
+
+	// el circuit breaker va antes que el rate limiting para que los urls de hosts caídos no consuman tokens
+	if cfg.Breaker.FailureThreshold > 0 {
+		breakers := NewBreakers(cfg.Breaker)
+		breakers.OnStateChange = func(host string, from, to BreakerState) {
+			logger.Warn("cambio de estado del circuito", "host", host, "from", from.String(), "to", to.String())
+		}
+		middlewares = append(middlewares, CircuitBreaker(breakers))
+	}
+
+	// robots.txt va después del circuit breaker para que el Crawl-delay solo demore a los hosts que responden
+	if cfg.Robots {
+		robots := NewRobots(client)
+		if requests.Default.UserAgent != "" {
+			robots.UserAgent = requests.Default.UserAgent
+		}
+		middlewares = append(middlewares, RespectRobots(robots))
+	}
+
+	// el hedging va antes que el rate limiting para que el segundo pedido también respete los límites
+	if cfg.Hedge.Percentile > 0 {
+		middlewares = append(middlewares, Hedge(cfg.Hedge))
+	}
+
+	limits := cfg.Limits
+	if limits.Rate > 0 || limits.HostRate > 0 || limits.MaxConnsPerHost > 0 {
+		middlewares = append(middlewares, RateLimit(NewLimiter(limits)))
+	}
+
+	// el timeout va último para que no cuente la espera del rate limiting ni del Crawl-delay, y con hedging cada
+	// pedido tiene el suyo
+	middlewares = append(middlewares, ProbeTimeout(cfg.Timeout))
+
+	cfg.tactics = middlewares
+
+	tcpProber, echoProber, dnsProber, grpcProber := NewTCPProber(), NewEchoProber(), NewDNSProber(), NewGRPCProber()
+	if !cfg.Network.IsZero() {
+		tcpProber.Dial, echoProber.Dial, grpcProber.Dial = dial, dial, dial
+		dnsProber.Resolver = resolver
+	}
+	if cfg.GRPCRootCAs != "" {
+		if err := grpcProber.LoadRootCAs(cfg.GRPCRootCAs); err != nil {
+			return nil, err
+		}
+	}
+
+	// el HEAD verifica los urls http y https, los demás esquemas tienen su propio Prober (ver probes.go y grpc.go)
+	probers := Schemes{
+		"http":  head,
+		"https": head,
+		"tcp":   tcpProber,
+		"echo":  echoProber,
+		"dns":   dnsProber,
+		"grpc":  grpcProber,
+		"grpcs": grpcProber,
+	}
+	return cfg.Wrap(probers, ""), nil
+}
+
+// Wrap envuelve p con las mismas tácticas que el Prober y con su mismo estado: los tokens del rate limiting,
+// los circuitos, el robots.txt y la cache se comparten. los resultados de p se guardan en la cache separados
+// por scope, vacío para los del Prober. se llama luego de Prober
+func (cfg *ProbeConfig) Wrap(p Prober, scope string) Prober {
+	middlewares := cfg.tactics
+	if cfg.cache != nil {
+		cached := Cached(cfg.cache)
+		if scope != "" {
+			cached = CachedScope(cfg.cache, scope)
+		}
+		middlewares = append([]Middleware{cached}, middlewares...)
+	}
+	return Chain(p, middlewares...)
+}
+
+// Client devuelve el cliente http con la configuración de red que usa el Prober, se llama luego de Prober
+func (cfg *ProbeConfig) Client() *http.Client {
+	return cfg.client
+}
+
+// Requests devuelve la configuración de los pedidos que usa el Prober, se llama luego de Prober
+func (cfg *ProbeConfig) Requests() *RequestConfigs {
+	return cfg.requests
+}
+
+// Close guarda el estado que las tácticas persisten entre ejecuciones (la cache con -cache-file),
+// se llama al terminar la ejecución
+func (cfg *ProbeConfig) Close() error {
+	if cfg.cache == nil {
+		return nil
+	}
+	return cfg.cache.Save()
+}
@@ -0,0 +1,24 @@
+/*
+	el paquete checker junta el código que comparten todos los ejemplos de concurrencia (secuencial, sync, channels
+	y pipes-filters) y que no hace a la táctica de concurrencia que muestra cada uno: el logging, los tipos de
+	verificación (HEAD, tcp, echo, dns y gRPC) y las tácticas que se aplican alrededor de cada verificación.
+
+	cada ejemplo lo importa con un replace en su go.mod, así sigue pudiendo ejecutarse con go run . desde su carpeta
+		require arqsoft/tacticas-arq-go/performance/concurrencia/checker v0.0.0
+		replace arqsoft/tacticas-arq-go/performance/concurrencia/checker => ../checker
+
+	todos los main siguen los mismos pasos:
+	  - RegisterLogFlags, RegisterProbeFlags, RegisterInventoryFlag y RegisterProfileFlags agregan los flags
+	    comunes. ProbeConfig.Prober arma el prober de cada url agregándole las tácticas que se pidieron por
+	    flags (rate limiting, circuit breaker, hedging, cache, criterios de éxito, etc.), así el código de cada
+	    estrategia solo llama a Probe y no cambia cuando se agrega una táctica.
+	  - antes de verificar se normaliza la lista con PrepareURLs: las entradas inválidas se reportan como
+	    "invalid input" sin hacer el HEAD y los urls repetidos se verifican una sola vez.
+	  - un url resulta true si cumple los criterios de éxito (por defecto responder 200, ver -expect-status y
+	    -expect en expect.go) y false si no los cumple. al final se registra un resumen (Summary) con los
+	    errores agrupados por categoría.
+	  - ProfileConfig.Start toma los perfiles de -cpuprofile, -memprofile, -blockprofile, -mutexprofile y
+	    -exectrace, sirve pprof con -pprof y ProfileConfig.Loop repite la verificación con -daemon hasta ctrl-c.
+*/
+
+package checker
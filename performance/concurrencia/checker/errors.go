@@ -0,0 +1,221 @@
+package checker
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+)
+
+/*
+	clasificación de los errores de una verificación.
+
+	los ejemplos originales reportan cualquier error del HEAD como "no existe", pero no es lo mismo un host que
+	no resuelve en el DNS que un servidor que rechaza la conexión, un certificado vencido o un timeout. para
+	saber por qué falló un sitio cada error se clasifica en un ErrorKind buscando con errors.As y errors.Is
+	dentro de los errores que envuelve el cliente http (url.Error, net.OpError, net.DNSError, etc.).
+*/
+
+// ErrorKind es la categoría del error de una verificación
+type ErrorKind int
+
+const (
+	// NoError es la categoría de un resultado sin error
+	NoError ErrorKind = iota
+	// DNSError indica que el host no se pudo resolver (no existe o falló el DNS)
+	DNSError
+	// ConnectRefused indica que el host existe pero rechazó la conexión
+	ConnectRefused
+	// TLSError indica que falló el handshake TLS o la verificación del certificado
+	TLSError
+	// Timeout indica que la verificación excedió su tiempo
+	Timeout
+	// Canceled indica que la verificación se canceló a través del context
+	Canceled
+	// TooManyRedirects indica que se cortó la cadena de redirecciones
+	TooManyRedirects
+	// RedirectNotAllowed indica que una redirección no respeta la política, ver RedirectPolicy
+	RedirectNotAllowed
+	// ProtocolError indica que la conexión se cortó o que la respuesta no es HTTP válido
+	ProtocolError
+	// InvalidInput indica que la entrada de la lista no es un url válido, ver PrepareURLs
+	InvalidInput
+	// NotChecked indica que el url no se verificó, por ejemplo con el circuito del host abierto
+	NotChecked
+	// UnknownError es cualquier otro error
+	UnknownError
+)
+
+var errorKindNames = map[ErrorKind]string{
+	NoError:            "none",
+	DNSError:           "dns",
+	ConnectRefused:     "connect_refused",
+	TLSError:           "tls",
+	Timeout:            "timeout",
+	Canceled:           "canceled",
+	TooManyRedirects:   "too_many_redirects",
+	RedirectNotAllowed: "redirect_not_allowed",
+	ProtocolError:      "protocol",
+	InvalidInput:       "invalid_input",
+	NotChecked:         "not_checked",
+	UnknownError:       "unknown",
+}
+
+// mensaje con el que las estrategias registran cada categoría en lugar del "no existe" de siempre
+var errorKindMessages = map[ErrorKind]string{
+	DNSError:           "no existe",
+	ConnectRefused:     "conexión rechazada",
+	TLSError:           "error de TLS",
+	Timeout:            "timeout",
+	Canceled:           "cancelado",
+	TooManyRedirects:   "demasiadas redirecciones",
+	RedirectNotAllowed: "redirección no permitida",
+	ProtocolError:      "error de protocolo",
+	InvalidInput:       "invalid input",
+	NotChecked:         "no verificado",
+	UnknownError:       "error desconocido",
+}
+
+// String devuelve el nombre de la categoría como aparece en los logs (campo error_kind)
+func (k ErrorKind) String() string {
+	if name, ok := errorKindNames[k]; ok {
+		return name
+	}
+	return errorKindNames[UnknownError]
+}
+
+// Message devuelve el mensaje con el que se registra un resultado con esta categoría de error
+func (k ErrorKind) Message() string {
+	if msg, ok := errorKindMessages[k]; ok {
+		return msg
+	}
+	return errorKindMessages[UnknownError]
+}
+
+// ParseErrorKind devuelve la categoría a partir de su nombre, UnknownError si no la conoce
+func ParseErrorKind(name string) ErrorKind {
+	for k, n := range errorKindNames {
+		if n == name {
+			return k
+		}
+	}
+	return UnknownError
+}
+
+// ProbeError es un error que ya tiene su categoría, por ejemplo el que se carga de la cache persistida
+// donde el error original se guardó como texto y no se puede volver a clasificar
+type ProbeError struct {
+	Kind ErrorKind
+	Err  error
+}
+
+func (e *ProbeError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ProbeError) Unwrap() error {
+	return e.Err
+}
+
+// Kind devuelve la categoría del error del resultado, NoError si no tiene
+func (r Result) Kind() ErrorKind {
+	return Classify(r.Err)
+}
+
+// Classify devuelve la categoría de un error. el orden importa: un timeout del DNS es un Timeout y no un
+// DNSError, y un context cancelado mientras se conectaba es Canceled aunque venga dentro de un net.OpError
+func Classify(err error) ErrorKind {
+	if err == nil {
+		return NoError
+	}
+
+	var probeErr *ProbeError
+	if errors.As(err, &probeErr) {
+		return probeErr.Kind
+	}
+	var invalid *InvalidURLError
+	if errors.As(err, &invalid) {
+		return InvalidInput
+	}
+	var skip *SkipError
+	if errors.As(err, &skip) {
+		return NotChecked
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return Canceled
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return Timeout
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return Timeout
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return DNSError
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return ConnectRefused
+	}
+	if isTLSError(err) {
+		return TLSError
+	}
+	if errors.Is(err, ErrTooManyRedirects) || isRedirectLimit(err) {
+		return TooManyRedirects
+	}
+	var redirectErr *RedirectPolicyError
+	if errors.As(err, &redirectErr) {
+		return RedirectNotAllowed
+	}
+	if isProtocolError(err) {
+		return ProtocolError
+	}
+	return UnknownError
+}
+
+// ErrTooManyRedirects es el error que devuelve una política de redirecciones al cortar la cadena
+var ErrTooManyRedirects = errors.New("demasiadas redirecciones")
+
+func isTLSError(err error) bool {
+	var (
+		recordErr    tls.RecordHeaderError
+		alertErr     tls.AlertError
+		verifyErr    *tls.CertificateVerificationError
+		authorityErr x509.UnknownAuthorityError
+		hostnameErr  x509.HostnameError
+		invalidErr   x509.CertificateInvalidError
+	)
+	return errors.As(err, &recordErr) || errors.As(err, &alertErr) || errors.As(err, &verifyErr) ||
+		errors.As(err, &authorityErr) || errors.As(err, &hostnameErr) || errors.As(err, &invalidErr)
+}
+
+// isRedirectLimit reconoce el error que devuelve la política por defecto de http.Client luego de 10
+// redirecciones, que no tiene un tipo propio y solo se puede distinguir por el texto
+func isRedirectLimit(err error) bool {
+	var urlErr *url.Error
+	return errors.As(err, &urlErr) && urlErr.Err != nil && strings.HasPrefix(urlErr.Err.Error(), "stopped after")
+}
+
+func isProtocolError(err error) bool {
+	var protoErr *http.ProtocolError
+	if errors.As(err, &protoErr) {
+		return true
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+	// el cliente http no exporta el error de una respuesta mal formada, ni el de un servidor que manda datos
+	// antes de recibir el pedido (readLoopPeekFailLocked)
+	msg := err.Error()
+	return strings.Contains(msg, "malformed HTTP") || strings.Contains(msg, "readLoopPeekFailLocked")
+}
@@ -0,0 +1,132 @@
+package checker
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// cada caso provoca el error real contra un servidor local y verifica su categoría
+func TestHeadProberClassifiesErrors(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer slow.Close()
+
+	loop := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, r.URL.Path, http.StatusFound)
+	}))
+	defer loop.Close()
+
+	secure := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer secure.Close()
+
+	// un puerto donde nadie escucha
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	refusedURL := "http://" + closed.Addr().String()
+	closed.Close()
+
+	// un servidor que no habla HTTP
+	garbage, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer garbage.Close()
+	go func() {
+		for {
+			conn, err := garbage.Accept()
+			if err != nil {
+				return
+			}
+			conn.Write([]byte("esto no es HTTP\r\n\r\n"))
+			conn.Close()
+		}
+	}()
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		client *http.Client
+		url    string
+		want   ErrorKind
+	}{
+		{"dns", context.Background(), http.DefaultClient, "http://no-existe.invalid", DNSError},
+		{"refused", context.Background(), http.DefaultClient, refusedURL, ConnectRefused},
+		{"tls", context.Background(), http.DefaultClient, secure.URL, TLSError},
+		{"timeout", context.Background(), &http.Client{Timeout: 50 * time.Millisecond}, slow.URL, Timeout},
+		{"canceled", canceled, http.DefaultClient, slow.URL, Canceled},
+		{"redirects", context.Background(), http.DefaultClient, loop.URL + "/loop", TooManyRedirects},
+		{"protocol", context.Background(), http.DefaultClient, "http://" + garbage.Addr().String(), ProtocolError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := NewHeadProber(tt.client).Probe(tt.ctx, tt.url)
+			if got := res.Kind(); got != tt.want {
+				t.Errorf("categoría = %s, se esperaba %s (error: %v)", got, tt.want, res.Err)
+			}
+		})
+	}
+}
+
+func TestClassifyKeepsKindOfWrappedErrors(t *testing.T) {
+	tests := []struct {
+		err  error
+		want ErrorKind
+	}{
+		{nil, NoError},
+		{&InvalidURLError{Input: "google.com", Reason: "falta el host"}, InvalidInput},
+		{&SkipError{Reason: "circuito abierto"}, NotChecked},
+		{&ProbeError{Kind: TLSError, Err: errors.New("x509: certificate has expired")}, TLSError},
+		{errors.New("otra cosa"), UnknownError},
+	}
+	for _, tt := range tests {
+		if got := Classify(tt.err); got != tt.want {
+			t.Errorf("Classify(%v) = %s, se esperaba %s", tt.err, got, tt.want)
+		}
+	}
+	for k := NoError; k <= UnknownError; k++ {
+		if got := ParseErrorKind(k.String()); got != k {
+			t.Errorf("ParseErrorKind(%q) = %s", k.String(), got)
+		}
+	}
+}
+
+func TestSummaryAggregatesByKind(t *testing.T) {
+	var s Summary
+	s.Add(
+		Result{StatusCode: http.StatusOK},
+		Result{StatusCode: http.StatusNotFound},
+		Result{Err: &ProbeError{Kind: DNSError, Err: errors.New("no such host")}},
+		Result{Err: &ProbeError{Kind: DNSError, Err: errors.New("no such host")}},
+		Result{Err: context.DeadlineExceeded},
+	)
+
+	errs := s.Errors()
+	if errs[DNSError] != 2 || errs[Timeout] != 1 || len(errs) != 2 {
+		t.Errorf("errores por categoría = %v", errs)
+	}
+
+	var buf bytes.Buffer
+	s.Log(slog.New(slog.NewTextHandler(&buf, nil)))
+	for _, want := range []string{"total=5", "ok=1", "false=1", "errors.dns=2", "errors.timeout=1"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("el resumen no tiene %s: %s", want, buf.String())
+		}
+	}
+}
@@ -0,0 +1,402 @@
+package checker
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+	criterios de éxito configurables.
+
+	los ejemplos originales consideran que un sitio responde solo si el HEAD devuelve 200, pero muchos endpoints
+	responden otra cosa sin estar caídos: un 204, un 301 a https o un 401 de una API que pide autenticación.
+	una Expectation define para un url:
+		- el conjunto de status aceptados, como rangos: "200-299,301,401"
+		- si se siguen las redirecciones y a dónde deben llevar
+		- el tiempo máximo de respuesta
+		- los headers que tiene que tener la respuesta
+		- el contenido del body: textos, expresiones regulares, valores JSON o el checksum
+	el HeadProber evalúa la Expectation de cada url y deja en Result.Unmet los criterios que no se cumplieron.
+
+	un HEAD solo dice que el servidor respondió, una página de mantenimiento servida con 200 pasa igual. los
+	criterios sobre el body hacen que se verifique con GET, leyendo como máximo MaxBodyBytes del body.
+*/
+
+// DefaultMaxBodyBytes es lo que se lee del body en un GET si los criterios no dicen otra cosa
+const DefaultMaxBodyBytes = 64 << 10
+
+// StatusRange es un rango de status code, Min y Max incluidos
+type StatusRange struct {
+	Min, Max int
+}
+
+// StatusSet es un conjunto de status code aceptados, vacío equivale a solo 200
+type StatusSet []StatusRange
+
+// ParseStatusSet lee un conjunto de status como "200-299,301,401"
+func ParseStatusSet(s string) (StatusSet, error) {
+	var set StatusSet
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		min, max, isRange := strings.Cut(part, "-")
+		if !isRange {
+			max = min
+		}
+		r, err := parseStatusRange(min, max)
+		if err != nil {
+			return nil, fmt.Errorf("status inválido %q: %w", part, err)
+		}
+		set = append(set, r)
+	}
+	return set, nil
+}
+
+func parseStatusRange(min, max string) (StatusRange, error) {
+	lo, err := strconv.Atoi(strings.TrimSpace(min))
+	if err != nil {
+		return StatusRange{}, err
+	}
+	hi, err := strconv.Atoi(strings.TrimSpace(max))
+	if err != nil {
+		return StatusRange{}, err
+	}
+	if lo < 100 || hi > 599 || lo > hi {
+		return StatusRange{}, fmt.Errorf("el rango debe estar entre 100 y 599")
+	}
+	return StatusRange{Min: lo, Max: hi}, nil
+}
+
+// Contains indica si el status code está en el conjunto
+func (s StatusSet) Contains(code int) bool {
+	if len(s) == 0 {
+		return code == http.StatusOK
+	}
+	for _, r := range s {
+		if code >= r.Min && code <= r.Max {
+			return true
+		}
+	}
+	return false
+}
+
+func (s StatusSet) String() string {
+	if len(s) == 0 {
+		return "200"
+	}
+	parts := make([]string, len(s))
+	for i, r := range s {
+		if r.Min == r.Max {
+			parts[i] = strconv.Itoa(r.Min)
+		} else {
+			parts[i] = fmt.Sprintf("%d-%d", r.Min, r.Max)
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// Expectation son los criterios de éxito de un url, el valor cero es el criterio de los ejemplos
+// originales: seguir las redirecciones y responder 200
+type Expectation struct {
+	Status StatusSet
+	// no seguir las redirecciones, el 3xx es la respuesta que se evalúa
+	NoRedirects bool
+	// prefijo del url al que tiene que llevar la redirección (el url final si se siguen, el header
+	// Location si no), vacío para no controlarlo
+	RedirectTo string
+	// tiempo máximo de respuesta, 0 sin máximo
+	MaxDuration time.Duration
+	// headers que tiene que tener la respuesta. con valor vacío alcanza con que esté, si no el header
+	// tiene que contener ese valor (así "application/json" acepta "application/json; charset=utf-8")
+	Headers map[string]string
+
+	// método del pedido, HEAD o GET. vacío es HEAD salvo que haya criterios sobre el body
+	Method string
+	// máximo de bytes del body que se leen, 0 para DefaultMaxBodyBytes
+	MaxBodyBytes int64
+	// textos que tiene que contener el body
+	BodyContains []string
+	// expresiones regulares que tiene que cumplir el body
+	BodyMatches []*regexp.Regexp
+	// valores que tiene que tener el body JSON por path, con los campos separados por puntos y los
+	// índices de los arrays como números: "status", "checks.db.status", "items.0.id"
+	JSON map[string]string
+	// sha256 del body en hexadecimal
+	BodySHA256 string
+}
+
+// method devuelve el método con el que se verifica el url
+func (e Expectation) method() string {
+	if e.Method != "" {
+		return strings.ToUpper(e.Method)
+	}
+	if len(e.BodyContains) > 0 || len(e.BodyMatches) > 0 || len(e.JSON) > 0 || e.BodySHA256 != "" {
+		return http.MethodGet
+	}
+	return http.MethodHead
+}
+
+func (e Expectation) maxBodyBytes() int64 {
+	if e.MaxBodyBytes > 0 {
+		return e.MaxBodyBytes
+	}
+	return DefaultMaxBodyBytes
+}
+
+// UnmarshalJSON lee una Expectation del archivo de criterios, con el status como texto ("200-299,301"),
+// el tiempo máximo como duración de Go ("500ms") y las expresiones regulares como texto
+func (e *Expectation) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Status          string            `json:"status"`
+		NoRedirects     bool              `json:"no_redirects"`
+		RedirectTo      string            `json:"redirect_to"`
+		MaxResponseTime string            `json:"max_response_time"`
+		Headers         map[string]string `json:"headers"`
+		Method          string            `json:"method"`
+		MaxBodyBytes    int64             `json:"max_body_bytes"`
+		BodyContains    []string          `json:"body_contains"`
+		BodyMatches     []string          `json:"body_matches"`
+		JSON            map[string]string `json:"json"`
+		BodySHA256      string            `json:"body_sha256"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	status, err := ParseStatusSet(raw.Status)
+	if err != nil {
+		return err
+	}
+	var maxDuration time.Duration
+	if raw.MaxResponseTime != "" {
+		if maxDuration, err = time.ParseDuration(raw.MaxResponseTime); err != nil {
+			return fmt.Errorf("max_response_time inválido: %w", err)
+		}
+	}
+	method := strings.ToUpper(raw.Method)
+	if method != "" && method != http.MethodHead && method != http.MethodGet {
+		return fmt.Errorf("method inválido %q: debe ser HEAD o GET", raw.Method)
+	}
+	matches := make([]*regexp.Regexp, 0, len(raw.BodyMatches))
+	for _, expr := range raw.BodyMatches {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return fmt.Errorf("body_matches inválido: %w", err)
+		}
+		matches = append(matches, re)
+	}
+	*e = Expectation{
+		Status:       status,
+		NoRedirects:  raw.NoRedirects,
+		RedirectTo:   raw.RedirectTo,
+		MaxDuration:  maxDuration,
+		Headers:      raw.Headers,
+		Method:       method,
+		MaxBodyBytes: raw.MaxBodyBytes,
+		BodyContains: raw.BodyContains,
+		BodyMatches:  matches,
+		JSON:         raw.JSON,
+		BodySHA256:   strings.ToLower(raw.BodySHA256),
+	}
+	return nil
+}
+
+// Check devuelve los criterios que no cumple el resultado de un pedido que respondió, vacío si los cumple
+// todos. body es lo que se leyó del body si el pedido fue un GET
+func (e Expectation) Check(res Result, body []byte) []string {
+	var unmet []string
+	if !e.Status.Contains(res.StatusCode) {
+		unmet = append(unmet, fmt.Sprintf("status %d no está en %s", res.StatusCode, e.Status))
+	}
+	if e.MaxDuration > 0 && res.Duration > e.MaxDuration {
+		unmet = append(unmet, fmt.Sprintf("demoró %s, el máximo es %s", res.Duration, e.MaxDuration))
+	}
+	if e.RedirectTo != "" {
+		target := res.FinalURL
+		if e.NoRedirects {
+			target = res.Header.Get("Location")
+		}
+		if !strings.HasPrefix(target, e.RedirectTo) {
+			unmet = append(unmet, fmt.Sprintf("redirige a %q, se esperaba %q", target, e.RedirectTo))
+		}
+	}
+	names := make([]string, 0, len(e.Headers))
+	for name := range e.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		want := e.Headers[name]
+		got, ok := res.Header[http.CanonicalHeaderKey(name)]
+		switch {
+		case !ok:
+			unmet = append(unmet, fmt.Sprintf("falta el header %s", name))
+		case want != "" && !strings.Contains(strings.Join(got, ", "), want):
+			unmet = append(unmet, fmt.Sprintf("el header %s es %q, se esperaba %q", name, strings.Join(got, ", "), want))
+		}
+	}
+	if res.Method == http.MethodGet {
+		unmet = append(unmet, e.checkBody(body)...)
+	}
+	return unmet
+}
+
+// checkBody evalúa los criterios sobre el contenido. si el body supera MaxBodyBytes los textos y
+// expresiones se buscan en lo que se leyó, pero el JSON y el checksum no se pueden verificar
+func (e Expectation) checkBody(body []byte) []string {
+	var unmet []string
+	truncated := int64(len(body)) > e.maxBodyBytes()
+	if truncated {
+		body = body[:e.maxBodyBytes()]
+	}
+
+	for _, want := range e.BodyContains {
+		if !bytes.Contains(body, []byte(want)) {
+			unmet = append(unmet, fmt.Sprintf("el body no contiene %q", want))
+		}
+	}
+	for _, re := range e.BodyMatches {
+		if !re.Match(body) {
+			unmet = append(unmet, fmt.Sprintf("el body no cumple %q", re))
+		}
+	}
+	if (len(e.JSON) > 0 || e.BodySHA256 != "") && truncated {
+		return append(unmet, fmt.Sprintf("el body supera los %d bytes, no se puede verificar el JSON ni el checksum", e.maxBodyBytes()))
+	}
+
+	if len(e.JSON) > 0 {
+		unmet = append(unmet, e.checkJSON(body)...)
+	}
+	if e.BodySHA256 != "" {
+		sum := sha256.Sum256(body)
+		if got := hex.EncodeToString(sum[:]); got != e.BodySHA256 {
+			unmet = append(unmet, fmt.Sprintf("el sha256 del body es %s, se esperaba %s", got, e.BodySHA256))
+		}
+	}
+	return unmet
+}
+
+func (e Expectation) checkJSON(body []byte) []string {
+	// los números se leen como json.Number, con el texto tal como viene en el body: como float64 un
+	// 100000000 se imprimiría 1e+08 y no sería igual al valor esperado
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return []string{fmt.Sprintf("el body no es JSON: %v", err)}
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return []string{"el body no es JSON: tiene datos después del valor"}
+	}
+
+	paths := make([]string, 0, len(e.JSON))
+	for path := range e.JSON {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var unmet []string
+	for _, path := range paths {
+		want := e.JSON[path]
+		value, ok := jsonPath(doc, path)
+		switch {
+		case !ok:
+			unmet = append(unmet, fmt.Sprintf("el JSON no tiene %s", path))
+		case fmt.Sprint(value) != want:
+			unmet = append(unmet, fmt.Sprintf("el JSON tiene %s = %v, se esperaba %q", path, value, want))
+		}
+	}
+	return unmet
+}
+
+// jsonPath busca el valor de un path separado por puntos en un documento JSON ya decodificado
+func jsonPath(doc any, path string) (any, bool) {
+	value := doc
+	for _, field := range strings.Split(strings.TrimPrefix(path, "$."), ".") {
+		switch v := value.(type) {
+		case map[string]any:
+			next, ok := v[field]
+			if !ok {
+				return nil, false
+			}
+			value = next
+		case []any:
+			i, err := strconv.Atoi(field)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, false
+			}
+			value = v[i]
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// Expectations son los criterios de éxito de todos los urls: los de Default salvo para los que
+// tienen criterios propios en URLs
+type Expectations struct {
+	Default Expectation
+	URLs    map[string]Expectation
+}
+
+// For devuelve los criterios de un url ya normalizado, con Expectations nil el criterio original
+func (e *Expectations) For(url string) Expectation {
+	if e == nil {
+		return Expectation{}
+	}
+	if exp, ok := e.URLs[url]; ok {
+		return exp
+	}
+	return e.Default
+}
+
+// LoadExpectations lee el archivo JSON de criterios, si no tiene "default" se usa def. los urls se
+// normalizan igual que la lista a verificar (ver Normalize) para que "HTTP://Google.com/" tome los
+// criterios de "http://google.com"
+func LoadExpectations(path string, def Expectation) (*Expectations, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Default *Expectation           `json:"default"`
+		URLs    map[string]Expectation `json:"urls"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	exps := &Expectations{Default: def, URLs: make(map[string]Expectation, len(file.URLs))}
+	if file.Default != nil {
+		exps.Default = *file.Default
+	}
+	// sin max_body_bytes propio se lee lo mismo que indica -max-body-bytes
+	if exps.Default.MaxBodyBytes == 0 {
+		exps.Default.MaxBodyBytes = def.MaxBodyBytes
+	}
+	for raw, exp := range file.URLs {
+		url, err := Normalize(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if exp.MaxBodyBytes == 0 {
+			exp.MaxBodyBytes = exps.Default.MaxBodyBytes
+		}
+		exps.URLs[url] = exp
+	}
+	return exps, nil
+}
@@ -0,0 +1,200 @@
+package checker
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseStatusSet(t *testing.T) {
+	set, err := ParseStatusSet("200-299, 301,401")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for code, want := range map[int]bool{200: true, 204: true, 299: true, 301: true, 401: true, 302: false, 404: false} {
+		if got := set.Contains(code); got != want {
+			t.Errorf("Contains(%d) = %v, se esperaba %v", code, got, want)
+		}
+	}
+	if got := set.String(); got != "200-299,301,401" {
+		t.Errorf("String() = %q", got)
+	}
+
+	for _, bad := range []string{"abc", "299-200", "99", "200-700"} {
+		if _, err := ParseStatusSet(bad); err == nil {
+			t.Errorf("ParseStatusSet(%q) debía fallar", bad)
+		}
+	}
+	if empty, _ := ParseStatusSet(""); !empty.Contains(http.StatusOK) || empty.Contains(http.StatusNoContent) {
+		t.Errorf("un conjunto vacío debe aceptar solo 200")
+	}
+}
+
+func TestHeadProberEvaluatesExpectations(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/vacio", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("/privado", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+	mux.HandleFunc("/viejo", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/nuevo", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/nuevo", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	})
+	mux.HandleFunc("/lento", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	exps := &Expectations{URLs: map[string]Expectation{
+		server.URL + "/vacio":   {Status: StatusSet{{200, 299}}},
+		server.URL + "/privado": {Status: StatusSet{{401, 401}}},
+		server.URL + "/viejo":   {Status: StatusSet{{301, 301}}, NoRedirects: true, RedirectTo: "/nuevo"},
+		server.URL + "/nuevo":   {Headers: map[string]string{"content-type": "application/json", "X-Version": ""}},
+		server.URL + "/lento":   {MaxDuration: 10 * time.Millisecond},
+	}}
+	prober := NewHeadProber(server.Client())
+	prober.Expectations = exps
+
+	tests := []struct {
+		path  string
+		ok    bool
+		unmet string
+	}{
+		{"/vacio", true, ""},
+		{"/privado", true, ""},
+		{"/viejo", true, ""},
+		{"/nuevo", false, "falta el header X-Version"},
+		{"/lento", false, "el máximo es 10ms"},
+		// sin criterios propios se usa el default: seguir la redirección y responder 200
+		{"/", false, "status 404"},
+	}
+	for _, tt := range tests {
+		res := prober.Probe(context.Background(), server.URL+tt.path)
+		if res.OK() != tt.ok {
+			t.Errorf("%s: OK() = %v, se esperaba %v (%+v)", tt.path, res.OK(), tt.ok, res.Unmet)
+		}
+		if tt.unmet != "" && !strings.Contains(strings.Join(res.Unmet, "; "), tt.unmet) {
+			t.Errorf("%s: unmet = %v, se esperaba %q", tt.path, res.Unmet, tt.unmet)
+		}
+	}
+}
+
+func TestLoadExpectationsNormalizesURLs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "expect.json")
+	data := `{
+		"urls": {
+			"HTTP://Api.Example.com:80/privado": {"status": "401", "max_response_time": "500ms"},
+			"https://example.com/": {"status": "301", "no_redirects": true, "redirect_to": "https://www.example.com"}
+		}
+	}`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	def := Expectation{Status: StatusSet{{200, 399}}, MaxBodyBytes: 1 << 20}
+	exps, err := LoadExpectations(path, def)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := exps.For("http://api.example.com/privado"); !got.Status.Contains(401) || got.MaxDuration != 500*time.Millisecond {
+		t.Errorf("criterios de /privado = %+v", got)
+	}
+	if got := exps.For("http://api.example.com/privado"); got.MaxBodyBytes != def.MaxBodyBytes {
+		t.Errorf("sin max_body_bytes se esperaba el de -max-body-bytes, es %d", got.MaxBodyBytes)
+	}
+	if got := exps.For("https://example.com"); !got.NoRedirects || got.RedirectTo != "https://www.example.com" {
+		t.Errorf("criterios de example.com = %+v", got)
+	}
+	if got := exps.For("http://otro.com"); got.Status.String() != "200-399" {
+		t.Errorf("sin default en el archivo se esperaba el de los flags, es %+v", got)
+	}
+}
+
+func TestHeadProberChecksBodyWithGET(t *testing.T) {
+	const health = `{"status":"ok","checks":{"db":{"status":"up"}},"items":[{"id":7}]}`
+	sum := sha256.Sum256([]byte(health))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Errorf("método = %s, con criterios sobre el body se esperaba GET", r.Method)
+		}
+		w.Write([]byte(health))
+	})
+	mux.HandleFunc("/mantenimiento", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html>sitio en mantenimiento</html>"))
+	})
+	mux.HandleFunc("/solo-get", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	exps := &Expectations{URLs: map[string]Expectation{
+		server.URL + "/health": {
+			BodyContains: []string{`"ok"`},
+			BodyMatches:  []*regexp.Regexp{regexp.MustCompile(`"id":\s*\d+`)},
+			JSON:         map[string]string{"status": "ok", "checks.db.status": "up", "items.0.id": "7"},
+			BodySHA256:   hex.EncodeToString(sum[:]),
+		},
+		server.URL + "/mantenimiento": {BodyContains: []string{"bienvenido"}},
+		server.URL + "/truncado":      {Method: http.MethodGet, MaxBodyBytes: 4, BodySHA256: "00"},
+	}}
+	prober := NewHeadProber(server.Client())
+	prober.Expectations = exps
+
+	if res := prober.Probe(context.Background(), server.URL+"/health"); !res.OK() || res.Method != http.MethodGet {
+		t.Errorf("/health debía cumplir todos los criterios con GET: %+v", res)
+	}
+	if res := prober.Probe(context.Background(), server.URL+"/mantenimiento"); res.OK() || !strings.Contains(res.Unmet[0], "bienvenido") {
+		t.Errorf("la página de mantenimiento con 200 no debía ser OK: %+v", res.Unmet)
+	}
+	// el servidor responde 404 pero el criterio que importa es que no se puede verificar el checksum
+	if res := prober.Probe(context.Background(), server.URL+"/truncado"); !strings.Contains(strings.Join(res.Unmet, "; "), "supera los 4 bytes") {
+		t.Errorf("se esperaba que el body supere el límite: %+v", res.Unmet)
+	}
+	if res := prober.Probe(context.Background(), server.URL+"/solo-get"); !res.OK() || res.Method != http.MethodGet {
+		t.Errorf("con 405 al HEAD se esperaba reintentar con GET: %+v", res)
+	}
+}
+
+func TestExpectationComparesJSONNumbersAsWritten(t *testing.T) {
+	body := []byte(`{"total": 100000000, "ratio": 0.5, "id": 12345678901234567890}`)
+	exp := Expectation{JSON: map[string]string{"total": "100000000", "ratio": "0.5", "id": "12345678901234567890"}}
+	if unmet := exp.checkJSON(body); len(unmet) > 0 {
+		t.Errorf("los números debían compararse con el texto del body: %v", unmet)
+	}
+	if unmet := exp.checkJSON(append(body, "{}"...)); len(unmet) != 1 {
+		t.Errorf("un body con datos después del JSON no es JSON: %v", unmet)
+	}
+}
+
+func TestExpectationUnmarshalRejectsInvalidCriteria(t *testing.T) {
+	for _, data := range []string{
+		`{"status": "abc"}`,
+		`{"max_response_time": "rápido"}`,
+		`{"method": "POST"}`,
+		`{"body_matches": ["("]}`,
+	} {
+		var exp Expectation
+		if err := json.Unmarshal([]byte(data), &exp); err == nil {
+			t.Errorf("%s debía fallar", data)
+		}
+	}
+}
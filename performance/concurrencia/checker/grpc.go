@@ -0,0 +1,153 @@
+package checker
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+/*
+	verificación de servicios gRPC con el protocolo estándar de health checking (grpc.health.v1.Health).
+
+	muchos servicios de backend no exponen HTTP sino gRPC, y el protocolo de health checking es la versión
+	gRPC del ping/echo: se llama al RPC Check con el nombre del servicio y el servidor contesta SERVING,
+	NOT_SERVING o UNKNOWN. solo SERVING es un éxito, los demás estados quedan en Result.Unmet.
+		- grpc://host:puerto?service=pagos   sin TLS
+		- grpcs://host:puerto?service=pagos  con TLS, con server_name=nombre para verificar el certificado
+		  contra otro nombre e insecure_skip_verify=true para no verificarlo (solo para pruebas)
+	sin service se pregunta por el estado general del servidor.
+*/
+
+// GRPCProber llama al Check de grpc.health.v1.Health del servidor
+type GRPCProber struct {
+	Timeout time.Duration
+	// RootCAs son las autoridades con las que se verifican los certificados de grpcs://, nil para las del sistema
+	RootCAs *x509.CertPool
+	// Dial abre las conexiones si no es nil, ver NetworkConfig
+	Dial DialFunc
+}
+
+func NewGRPCProber() *GRPCProber {
+	return &GRPCProber{Timeout: DefaultProbeTimeout}
+}
+
+// LoadRootCAs carga en el prober las autoridades de un archivo PEM, para servidores con certificados propios
+func (p *GRPCProber) LoadRootCAs(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return fmt.Errorf("%s: no tiene certificados PEM", path)
+	}
+	p.RootCAs = pool
+	return nil
+}
+
+func (p *GRPCProber) Probe(ctx context.Context, rawURL string) Result {
+	res := Result{URL: rawURL, Attempt: 1}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	query := u.Query()
+
+	creds := insecure.NewCredentials()
+	if u.Scheme == "grpcs" {
+		creds = credentials.NewTLS(&tls.Config{
+			RootCAs:            p.RootCAs,
+			ServerName:         query.Get("server_name"),
+			InsecureSkipVerify: query.Get("insecure_skip_verify") == "true",
+		})
+	}
+	// la conexión se abre recién con el primer RPC, dentro del timeout
+	target := u.Host
+	options := []grpc.DialOption{grpc.WithTransportCredentials(creds)}
+	if p.Dial != nil {
+		// con passthrough gRPC no resuelve el nombre por su cuenta y se lo pasa tal cual a Dial
+		target = "passthrough:///" + u.Host
+		options = append(options, grpc.WithContextDialer(func(ctx context.Context, address string) (net.Conn, error) {
+			return p.Dial(ctx, "tcp", address)
+		}))
+	}
+	conn, err := grpc.NewClient(target, options...)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	defer conn.Close()
+
+	ctx, cancel := withTimeout(ctx, p.Timeout)
+	defer cancel()
+
+	service := query.Get("service")
+	start := time.Now()
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+	res.Duration = time.Since(start)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			// el servidor contesta pero no conoce el servicio
+			res.Health = "SERVICE_UNKNOWN"
+			res.Unmet = []string{fmt.Sprintf("el servidor no conoce el servicio %q", service)}
+			res.Evaluated = true
+			return res
+		}
+		res.Err = grpcError(ctx, err)
+		return res
+	}
+
+	res.Health = resp.GetStatus().String()
+	if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		res.Unmet = []string{fmt.Sprintf("estado %s", res.Health)}
+	}
+	res.Evaluated = true
+	return res
+}
+
+// grpcError clasifica el error de un RPC. gRPC devuelve los errores de conexión como un status con el
+// texto del error original, por eso acá no alcanza con errors.As y se mira el código y el mensaje
+func grpcError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	kind := UnknownError
+	msg := status.Convert(err).Message()
+	switch status.Code(err) {
+	case codes.DeadlineExceeded:
+		kind = Timeout
+	case codes.Canceled:
+		kind = Canceled
+	case codes.Unimplemented:
+		// el servidor responde gRPC pero no implementa el protocolo de health checking
+		kind = ProtocolError
+	case codes.Unavailable:
+		switch {
+		case strings.Contains(msg, "connection refused"):
+			kind = ConnectRefused
+		case strings.Contains(msg, "no such host") || strings.Contains(msg, "produced zero addresses"):
+			kind = DNSError
+		case strings.Contains(msg, "tls:") || strings.Contains(msg, "x509:") || strings.Contains(msg, "authentication handshake failed"):
+			kind = TLSError
+		default:
+			kind = ProtocolError
+		}
+	}
+	return &ProbeError{Kind: kind, Err: errors.New(msg)}
+}
@@ -0,0 +1,73 @@
+package checker
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// startHealthServer levanta en el proceso un servidor gRPC con el servicio estándar de health checking,
+// pagos responde SERVING y reportes NOT_SERVING
+func startHealthServer(t *testing.T, opts ...grpc.ServerOption) string {
+	t.Helper()
+	ln := listen(t)
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("pagos", healthpb.HealthCheckResponse_SERVING)
+	healthServer.SetServingStatus("reportes", healthpb.HealthCheckResponse_NOT_SERVING)
+
+	server := grpc.NewServer(opts...)
+	healthpb.RegisterHealthServer(server, healthServer)
+	go server.Serve(ln)
+	t.Cleanup(server.Stop)
+	return ln.Addr().String()
+}
+
+func TestGRPCProberMapsHealthStatus(t *testing.T) {
+	addr := startHealthServer(t)
+	closed := listen(t)
+	closed.Close()
+
+	tests := []struct {
+		url    string
+		ok     bool
+		health string
+		kind   ErrorKind
+	}{
+		{"grpc://" + addr, true, "SERVING", NoError},
+		{"grpc://" + addr + "?service=pagos", true, "SERVING", NoError},
+		{"grpc://" + addr + "?service=reportes", false, "NOT_SERVING", NoError},
+		{"grpc://" + addr + "?service=inventario", false, "SERVICE_UNKNOWN", NoError},
+		{"grpc://" + closed.Addr().String(), false, "", ConnectRefused},
+	}
+	prober := NewGRPCProber()
+	for _, tt := range tests {
+		res := prober.Probe(context.Background(), tt.url)
+		if res.OK() != tt.ok || res.Health != tt.health || res.Kind() != tt.kind {
+			t.Errorf("%s: ok=%v health=%q kind=%s (error: %v), se esperaba ok=%v health=%q kind=%s",
+				tt.url, res.OK(), res.Health, res.Kind(), res.Err, tt.ok, tt.health, tt.kind)
+		}
+	}
+}
+
+func TestGRPCProberWithTLS(t *testing.T) {
+	// el mismo certificado autofirmado que usan los tests de TLS, acá vencido no importa porque no se verifica
+	cert, _ := expiredCertificate(t)
+	addr := startHealthServer(t, grpc.Creds(credentials.NewServerTLSFromCert(&cert)))
+
+	prober := NewGRPCProber()
+	if res := prober.Probe(context.Background(), "grpcs://"+addr+"?service=pagos&insecure_skip_verify=true"); !res.OK() {
+		t.Errorf("con insecure_skip_verify se esperaba SERVING: %+v", res)
+	}
+	if res := prober.Probe(context.Background(), "grpcs://"+addr+"?service=pagos"); res.Kind() != TLSError {
+		t.Errorf("sin confiar en el certificado se esperaba tls, es %s (%v)", res.Kind(), res.Err)
+	}
+	// un servidor con TLS no entiende a un cliente sin TLS
+	if res := prober.Probe(context.Background(), "grpc://"+addr); res.OK() {
+		t.Errorf("sin TLS contra un servidor con TLS no debía responder: %+v", res)
+	}
+}
@@ -0,0 +1,141 @@
+package checker
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+/*
+	hedged requests (táctica de performance para bajar la latencia de la cola).
+
+	el tiempo de una ejecución lo define el HEAD más lento. con hedging, si una verificación no respondió luego
+	de un cierto percentil de las latencias observadas (por ejemplo el p95) se lanza un segundo HEAD idéntico y
+	gana la primera respuesta. al que pierde se lo cancela a través del context.
+
+	el costo es hacer más pedidos: con el p95 como umbral se repiten alrededor del 5% de las verificaciones.
+*/
+
+// HedgeConfig es la configuración del hedging, con Percentile 0 no se usa
+type HedgeConfig struct {
+	// percentil (entre 0 y 100) de las latencias observadas a partir del cual se lanza el segundo pedido
+	Percentile float64
+	// demora antes de lanzar el segundo pedido mientras no hay suficientes latencias observadas
+	Delay time.Duration
+	// cantidad de latencias recientes sobre las que se calcula el percentil
+	Window int
+}
+
+// cantidad mínima de latencias observadas para usar el percentil en lugar de Delay
+const hedgeMinSamples = 10
+
+// Hedge es el Middleware que lanza un segundo pedido si el primero demora más que el percentil configurado.
+// el Attempt del resultado indica cuál de los dos ganó
+func Hedge(cfg HedgeConfig) Middleware {
+	window := newLatencyWindow(cfg.Window)
+
+	return func(next Prober) Prober {
+		return ProberFunc(func(ctx context.Context, url string) Result {
+			delay := cfg.Delay
+			if d, ok := window.percentile(cfg.Percentile); ok {
+				delay = d
+			}
+
+			// al salir se cancela el pedido que perdió
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+
+			start := time.Now()
+			// con capacidad 2 el que pierde puede escribir su resultado aunque nadie lo lea y terminar
+			results := make(chan Result, 2)
+			launch := func(attempt int) {
+				go func() {
+					res := next.Probe(ctx, url)
+					res.Attempt = attempt
+					results <- res
+				}()
+			}
+
+			launch(1)
+			timer := time.NewTimer(delay)
+			defer timer.Stop()
+
+			var res Result
+			select {
+			case res = <-results:
+			case <-timer.C:
+				launch(2)
+				res = <-results
+			case <-ctx.Done():
+				return Result{URL: url, Attempt: 1, Err: ctx.Err(), Duration: time.Since(start)}
+			}
+
+			// la ventana guarda la latencia de un pedido, el resultado la que esperó quien llamó
+			if res.Err == nil {
+				window.add(res.Duration)
+			}
+			res.Duration = time.Since(start)
+			return res
+		})
+	}
+}
+
+// latencyWindow guarda las últimas latencias observadas en un buffer circular
+type latencyWindow struct {
+	mu      sync.Mutex
+	samples []time.Duration
+	next    int
+	full    bool
+}
+
+func newLatencyWindow(size int) *latencyWindow {
+	if size < hedgeMinSamples {
+		size = hedgeMinSamples
+	}
+	return &latencyWindow{samples: make([]time.Duration, size)}
+}
+
+func (w *latencyWindow) add(d time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.samples[w.next] = d
+	w.next = (w.next + 1) % len(w.samples)
+	if w.next == 0 {
+		w.full = true
+	}
+}
+
+// percentile devuelve el percentil p de las latencias observadas, false si todavía no hay suficientes
+func (w *latencyWindow) percentile(p float64) (time.Duration, bool) {
+	w.mu.Lock()
+	n := w.next
+	if w.full {
+		n = len(w.samples)
+	}
+	if n < hedgeMinSamples {
+		w.mu.Unlock()
+		return 0, false
+	}
+	sorted := append([]time.Duration(nil), w.samples[:n]...)
+	w.mu.Unlock()
+
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return Percentile(sorted, p), true
+}
+
+// Percentile devuelve el percentil p (entre 0 y 100) de un slice de duraciones ordenado de menor a mayor
+func Percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(p / 100 * float64(len(sorted)))
+	if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
@@ -0,0 +1,80 @@
+package checker
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHedgeSecondRequestWinsAndLoserIsCanceled(t *testing.T) {
+	var calls int32
+	canceled := make(chan struct{})
+	slowFirst := ProberFunc(func(ctx context.Context, url string) Result {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			select {
+			case <-time.After(time.Second):
+			case <-ctx.Done():
+				close(canceled)
+				return Result{URL: url, Err: ctx.Err()}
+			}
+		}
+		return Result{URL: url, StatusCode: 200, Duration: time.Millisecond}
+	})
+
+	prober := Hedge(HedgeConfig{Percentile: 95, Delay: 20 * time.Millisecond})(slowFirst)
+	res := prober.Probe(context.Background(), "http://lento.com")
+
+	if !res.OK() || res.Attempt != 2 {
+		t.Fatalf("se esperaba que ganara el segundo pedido: %+v", res)
+	}
+	if res.Duration >= 500*time.Millisecond {
+		t.Errorf("el hedging no bajó la latencia: %v", res.Duration)
+	}
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Error("el pedido que perdió no se canceló")
+	}
+}
+
+func TestHedgeDoesNotDuplicateFastRequests(t *testing.T) {
+	var calls int32
+	fast := ProberFunc(func(ctx context.Context, url string) Result {
+		atomic.AddInt32(&calls, 1)
+		return Result{URL: url, StatusCode: 200}
+	})
+
+	prober := Hedge(HedgeConfig{Percentile: 95, Delay: time.Second})(fast)
+	for i := 0; i < 5; i++ {
+		if res := prober.Probe(context.Background(), "http://rapido.com"); res.Attempt != 1 {
+			t.Errorf("un pedido rápido no debe repetirse: %+v", res)
+		}
+	}
+	if calls != 5 {
+		t.Errorf("llamados = %d, se esperaban 5", calls)
+	}
+}
+
+func TestLatencyWindowPercentile(t *testing.T) {
+	w := newLatencyWindow(20)
+	for i := 1; i < hedgeMinSamples; i++ {
+		w.add(time.Duration(i) * time.Millisecond)
+	}
+	if _, ok := w.percentile(95); ok {
+		t.Error("no debe calcular el percentil con menos de hedgeMinSamples latencias")
+	}
+	for i := hedgeMinSamples; i <= 20; i++ {
+		w.add(time.Duration(i) * time.Millisecond)
+	}
+	if p, ok := w.percentile(50); !ok || p != 11*time.Millisecond {
+		t.Errorf("p50 = %v, se esperaba 11ms", p)
+	}
+	// el buffer es circular, las latencias nuevas reemplazan a las más viejas
+	for i := 0; i < 20; i++ {
+		w.add(100 * time.Millisecond)
+	}
+	if p, _ := w.percentile(0); p != 100*time.Millisecond {
+		t.Errorf("p0 = %v, se esperaba 100ms", p)
+	}
+}
@@ -0,0 +1,108 @@
+package checker
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Inventory es el archivo con la lista de urls a verificar, uno por línea. las líneas vacías y las que
+// empiezan con # se ignoran. en la lista se pueden mezclar los distintos tipos de verificación:
+//
+//	http://google.com
+//	tcp://db.internal:5432
+//	echo://pagos.internal:7007
+//	dns://google.com?type=A
+//
+// además de (o en lugar de) el archivo los urls se pueden tomar de los sitemaps de los sitios, ver LoadSitemap
+type Inventory struct {
+	Path string
+	// urls de sitemaps separados por comas y máximo de urls que se toman de ellos
+	Sitemaps   string
+	SitemapMax int
+
+	// los sitemaps se descargan con el cliente y la configuración de los pedidos del prober
+	probe *ProbeConfig
+}
+
+// tiempo máximo para descargar los sitemaps
+const sitemapTimeout = 30 * time.Second
+
+// RegisterInventoryFlag agrega los flags -inventory, -sitemap y -sitemap-max al FlagSet. los sitemaps se
+// descargan con la misma red, transport, User-Agent y headers que las verificaciones de probe, por eso
+// URLs se llama luego de probe.Prober
+func RegisterInventoryFlag(fs *flag.FlagSet, probe *ProbeConfig) *Inventory {
+	inv := &Inventory{probe: probe}
+	fs.StringVar(&inv.Path, "inventory", "", "archivo con los urls a verificar, uno por línea (por defecto la lista del ejemplo)")
+	fs.StringVar(&inv.Sitemaps, "sitemap", "", "urls de sitemap.xml (o índices de sitemaps) separados por comas de donde tomar los urls a verificar")
+	fs.IntVar(&inv.SitemapMax, "sitemap-max", 1000, "máximo de urls que se toman entre todos los sitemaps")
+	return inv
+}
+
+// URLs devuelve los urls del archivo y de los sitemaps, o defaults si no se indicó ninguno
+func (inv *Inventory) URLs(defaults []string) ([]string, error) {
+	if inv.Path == "" && inv.Sitemaps == "" {
+		return defaults, nil
+	}
+
+	urls, err := inv.file()
+	if err != nil {
+		return nil, err
+	}
+
+	var client *http.Client
+	var requests *RequestConfigs
+	if inv.probe != nil {
+		client, requests = inv.probe.Client(), inv.probe.Requests()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), sitemapTimeout)
+	defer cancel()
+	// el máximo es del total de los sitemaps, cada uno recibe lo que dejaron los anteriores
+	remaining := inv.SitemapMax
+	if remaining <= 0 {
+		remaining = MaxSitemapURLs
+	}
+	for _, sitemap := range strings.Split(inv.Sitemaps, ",") {
+		if sitemap = strings.TrimSpace(sitemap); sitemap == "" {
+			continue
+		}
+		if remaining <= 0 {
+			break
+		}
+		loaded, err := LoadSitemap(ctx, client, requests, sitemap, remaining)
+		if err != nil {
+			return nil, err
+		}
+		urls = append(urls, loaded...)
+		remaining -= len(loaded)
+	}
+	return urls, nil
+}
+
+// file devuelve los urls del archivo, ninguno si no se indicó
+func (inv *Inventory) file() ([]string, error) {
+	if inv.Path == "" {
+		return nil, nil
+	}
+
+	f, err := os.Open(inv.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var urls []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		urls = append(urls, line)
+	}
+	return urls, scanner.Err()
+}
@@ -0,0 +1,97 @@
+package checker
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+)
+
+// nombres de los campos que usan todos los ejemplos en sus logs, así los registros de las distintas
+// estrategias se pueden filtrar y comparar con las mismas claves
+const (
+	KeyRunID    = "run_id"
+	KeyURL      = "url"
+	KeyStage    = "stage"
+	KeyWorkerID = "worker_id"
+	KeyAttempt  = "attempt"
+	KeyDuration = "duration"
+	KeyError    = "error"
+	KeyStatus   = "status"
+	// tiempo que se esperó por el rate limiting
+	KeyThrottled = "throttled"
+	// el resultado salió de la cache o se compartió con otra verificación en vuelo
+	KeyCached = "cached"
+	KeyShared = "shared"
+	// categoría del error, ver ErrorKind
+	KeyErrorKind = "error_kind"
+	// url al que llevaron las redirecciones y criterios de éxito que no se cumplieron, ver Expectation
+	KeyFinalURL = "final_url"
+	KeyUnmet    = "unmet"
+	// cadena de redirecciones, ver Redirect
+	KeyRedirects = "redirects"
+	// método con el que se verificó el url cuando no es HEAD
+	KeyMethod = "method"
+	// respuestas de una verificación dns://
+	KeyAnswers = "answers"
+	// estado de un servicio gRPC
+	KeyHealth = "health"
+	// vencimiento más próximo de la cadena de certificados y advertencias sobre los certificados, ver TLSInfo
+	KeyCertNotAfter = "cert_not_after"
+	KeyCertWarning  = "cert_warning"
+	// página donde se encontró el link y saltos desde los urls iniciales en el modo crawler
+	KeyReferrer = "referrer"
+	KeyDepth    = "depth"
+)
+
+// LogConfig es la configuración del logger que se toma de los flags de cada ejemplo
+type LogConfig struct {
+	Format string
+	Level  string
+}
+
+// RegisterLogFlags agrega los flags -log-format y -log-level al FlagSet y devuelve la configuración
+// que queda cargada luego de fs.Parse
+func RegisterLogFlags(fs *flag.FlagSet) *LogConfig {
+	cfg := &LogConfig{}
+	fs.StringVar(&cfg.Format, "log-format", "text", "formato de los logs: text o json")
+	fs.StringVar(&cfg.Level, "log-level", "info", "nivel mínimo de los logs: debug, info, warn o error")
+	return cfg
+}
+
+// Logger crea el logger de una ejecución, todos sus registros llevan el run_id para poder separar
+// las distintas ejecuciones en el agregador de logs
+func (cfg *LogConfig) Logger(w io.Writer) (*slog.Logger, error) {
+	logger, err := NewLogger(w, cfg.Format, cfg.Level)
+	if err != nil {
+		return nil, err
+	}
+	return logger.With(KeyRunID, NewRunID()), nil
+}
+
+// NewLogger crea un logger con el handler de texto o JSON de slog y el nivel indicado
+func NewLogger(w io.Writer, format, level string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("nivel de log inválido %q: %w", level, err)
+	}
+	opts := &slog.HandlerOptions{Level: lvl}
+
+	switch format {
+	case "text":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	default:
+		return nil, fmt.Errorf("formato de log inválido %q, debe ser text o json", format)
+	}
+}
+
+// NewRunID genera un identificador al azar para una ejecución
+func NewRunID() string {
+	var id [8]byte
+	rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
@@ -0,0 +1,58 @@
+package checker
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"strings"
+	"testing"
+)
+
+func TestLoggerFromFlagsWritesJSONWithRunID(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	cfg := RegisterLogFlags(fs)
+	if err := fs.Parse([]string{"-log-format=json", "-log-level=debug"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	logger, err := cfg.Logger(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.Debug("url verificado", KeyURL, "http://example.com", KeyWorkerID, 2)
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("el registro no es JSON: %v (%s)", err, buf.String())
+	}
+	if record[KeyRunID] == "" || record[KeyRunID] == nil {
+		t.Errorf("falta el run_id en %v", record)
+	}
+	if record[KeyURL] != "http://example.com" {
+		t.Errorf("url = %v", record[KeyURL])
+	}
+}
+
+func TestLoggerFiltersByLevel(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := NewLogger(&buf, "text", "warn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.Info("no se debe ver")
+	logger.Warn("se debe ver")
+
+	if strings.Contains(buf.String(), "no se debe ver") || !strings.Contains(buf.String(), "se debe ver") {
+		t.Errorf("el filtro por nivel no funciona: %q", buf.String())
+	}
+}
+
+func TestNewLoggerRejectsInvalidConfig(t *testing.T) {
+	if _, err := NewLogger(&bytes.Buffer{}, "xml", "info"); err == nil {
+		t.Error("se esperaba error con formato xml")
+	}
+	if _, err := NewLogger(&bytes.Buffer{}, "text", "verbose"); err == nil {
+		t.Error("se esperaba error con nivel verbose")
+	}
+}
@@ -0,0 +1,170 @@
+package checker
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+/*
+	configuración de red: proxy, resolver de DNS, hosts fijos y dirección de origen.
+
+	para verificar los sitios desde distintos puntos de la red (desde adentro y desde afuera de la red de la
+	empresa) no alcanza con la red por defecto de la máquina. la NetworkConfig cambia cómo se conecta cada
+	verificación:
+		- Proxy: los pedidos http y https pasan por un proxy http://, https:// o socks5:// (sin Proxy se usan las
+		  variables HTTP_PROXY, HTTPS_PROXY y NO_PROXY como siempre)
+		- DNSServer: los nombres se resuelven con ese servidor (host:puerto) en lugar del de la máquina
+		- Hosts: nombres con una IP fija, como las entradas de /etc/hosts pero solo para esta ejecución
+		- LocalAddr: la IP local desde la que salen las conexiones, para elegir la interfaz de red
+	el resolver, los hosts y la dirección local valen para todos los tipos de verificación (http, tcp, echo, gRPC;
+	dns solo usa el servidor DNS), el proxy solo para http y https. el cliente http se arma con NewClient.
+*/
+
+// DialFunc abre una conexión, tiene la misma firma que net.Dialer.DialContext
+type DialFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+// NetworkConfig es la configuración de red de las verificaciones, el valor cero es la red por defecto
+type NetworkConfig struct {
+	Proxy     string
+	DNSServer string
+	Hosts     map[string]string
+	LocalAddr string
+}
+
+// IsZero indica si la configuración es la red por defecto
+func (cfg NetworkConfig) IsZero() bool {
+	return cfg.Proxy == "" && cfg.DNSServer == "" && len(cfg.Hosts) == 0 && cfg.LocalAddr == ""
+}
+
+// Resolver devuelve el resolver del servidor DNS configurado, net.DefaultResolver si no hay uno
+func (cfg NetworkConfig) Resolver() (*net.Resolver, error) {
+	if cfg.DNSServer == "" {
+		return net.DefaultResolver, nil
+	}
+	server := cfg.DNSServer
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "53")
+	}
+	dialer, err := cfg.dialer(nil)
+	if err != nil {
+		return nil, err
+	}
+	return &net.Resolver{
+		// el resolver de Go es el único que permite elegir el servidor, el de cgo usa siempre el del sistema
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return dialer(ctx, network, server)
+		},
+	}, nil
+}
+
+// DialContext devuelve la función con la que se abren las conexiones: resuelve con los Hosts fijos o con el
+// resolver configurado y sale desde LocalAddr
+func (cfg NetworkConfig) DialContext() (DialFunc, error) {
+	resolver, err := cfg.Resolver()
+	if err != nil {
+		return nil, err
+	}
+	dial, err := cfg.dialer(resolver)
+	if err != nil {
+		return nil, err
+	}
+	if len(cfg.Hosts) == 0 {
+		return dial, nil
+	}
+
+	hosts := make(map[string]string, len(cfg.Hosts))
+	for name, ip := range cfg.Hosts {
+		hosts[strings.ToLower(name)] = ip
+	}
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(address)
+		if err == nil {
+			if ip, ok := hosts[strings.ToLower(host)]; ok {
+				address = net.JoinHostPort(ip, port)
+			}
+		}
+		return dial(ctx, network, address)
+	}, nil
+}
+
+// dialer arma un net.Dialer con el resolver y la dirección local. la dirección local depende de la red
+// (TCPAddr o UDPAddr, el resolver habla por las dos), por eso se elige en cada conexión
+func (cfg NetworkConfig) dialer(resolver *net.Resolver) (DialFunc, error) {
+	var local net.IP
+	if cfg.LocalAddr != "" {
+		if local = net.ParseIP(cfg.LocalAddr); local == nil {
+			return nil, fmt.Errorf("la dirección local %q no es una IP", cfg.LocalAddr)
+		}
+	}
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		d := net.Dialer{Resolver: resolver}
+		if local != nil {
+			if strings.HasPrefix(network, "udp") {
+				d.LocalAddr = &net.UDPAddr{IP: local}
+			} else {
+				d.LocalAddr = &net.TCPAddr{IP: local}
+			}
+		}
+		return d.DialContext(ctx, network, address)
+	}, nil
+}
+
+// proxy devuelve la función de proxy del http.Transport: el Proxy configurado o el de las variables de entorno
+func (cfg NetworkConfig) proxy() (func(*http.Request) (*url.URL, error), error) {
+	if cfg.Proxy == "" {
+		return http.ProxyFromEnvironment, nil
+	}
+	u, err := url.Parse(cfg.Proxy)
+	if err != nil {
+		return nil, fmt.Errorf("proxy: %w", err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("proxy: el esquema debe ser http, https o socks5 y es %q", u.Scheme)
+	}
+	return http.ProxyURL(u), nil
+}
+
+// ParseHostOverride lee una entrada "nombre=ip" de -resolve
+func ParseHostOverride(entry string) (name, ip string, err error) {
+	name, ip, ok := strings.Cut(entry, "=")
+	name, ip = strings.TrimSpace(name), strings.TrimSpace(ip)
+	if !ok || name == "" || net.ParseIP(ip) == nil {
+		return "", "", fmt.Errorf("%q no tiene la forma nombre=ip", entry)
+	}
+	return name, ip, nil
+}
+
+// LoadHostsFile lee un archivo con el formato de /etc/hosts (una IP seguida de sus nombres, # para comentarios)
+// y agrega sus entradas a hosts
+func LoadHostsFile(path string, hosts map[string]string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if net.ParseIP(fields[0]) == nil || len(fields) < 2 {
+			return fmt.Errorf("%s:%d: se esperaba una IP seguida de nombres", path, n)
+		}
+		for _, name := range fields[1:] {
+			hosts[name] = fields[0]
+		}
+	}
+	return scanner.Err()
+}
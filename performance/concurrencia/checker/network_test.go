@@ -0,0 +1,160 @@
+package checker
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestNetworkHostsOverrideResolution(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	_, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+
+	network := NetworkConfig{Hosts: map[string]string{"Sitio.invalid": "127.0.0.1"}}
+	client, err := NewClient(DefaultTransportConfig(), network)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res := NewHeadProber(client).Probe(context.Background(), "http://sitio.invalid:"+port); !res.OK() {
+		t.Errorf("sitio.invalid debía resolverse a 127.0.0.1: %+v", res)
+	}
+
+	dial, err := network.DialContext()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tcp := NewTCPProber()
+	tcp.Dial = dial
+	if res := tcp.Probe(context.Background(), "tcp://sitio.invalid:"+port); res.Err != nil {
+		t.Errorf("tcp:// también debía usar los hosts fijos: %+v", res)
+	}
+}
+
+func TestNetworkProxy(t *testing.T) {
+	var requested string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// a un proxy http le llega el url completo
+		requested = r.URL.String()
+	}))
+	defer proxy.Close()
+
+	client, err := NewClient(DefaultTransportConfig(), NetworkConfig{Proxy: proxy.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res := NewHeadProber(client).Probe(context.Background(), "http://destino.invalid/health"); !res.OK() {
+		t.Fatalf("el pedido debía pasar por el proxy: %+v", res)
+	}
+	if requested != "http://destino.invalid/health" {
+		t.Errorf("el proxy recibió %q", requested)
+	}
+
+	if _, err := NewClient(DefaultTransportConfig(), NetworkConfig{Proxy: "ftp://proxy:21"}); err == nil {
+		t.Error("un proxy ftp:// debía dar error")
+	}
+}
+
+// serveDNS atiende consultas A con una IP fija para cualquier nombre, devuelve el host:puerto del servidor
+func serveDNS(t *testing.T, ip [4]byte) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var query dnsmessage.Message
+			if err := query.Unpack(buf[:n]); err != nil || len(query.Questions) == 0 {
+				continue
+			}
+			q := query.Questions[0]
+			reply := dnsmessage.Message{
+				Header:    dnsmessage.Header{ID: query.ID, Response: true, Authoritative: true},
+				Questions: query.Questions,
+			}
+			if q.Type == dnsmessage.TypeA {
+				reply.Answers = []dnsmessage.Resource{{
+					Header: dnsmessage.ResourceHeader{Name: q.Name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 60},
+					Body:   &dnsmessage.AResource{A: ip},
+				}}
+			}
+			packed, err := reply.Pack()
+			if err == nil {
+				conn.WriteTo(packed, addr)
+			}
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestNetworkDNSServer(t *testing.T) {
+	server := serveDNS(t, [4]byte{10, 1, 2, 3})
+	resolver, err := NetworkConfig{DNSServer: server}.Resolver()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dns := NewDNSProber()
+	dns.Resolver = resolver
+	res := dns.Probe(context.Background(), "dns://interno.ejemplo.test?expect=10.1.2.3")
+	if !res.OK() {
+		t.Errorf("el nombre debía resolverse con el servidor DNS configurado: %+v", res)
+	}
+}
+
+func TestNetworkLocalAddr(t *testing.T) {
+	var remote string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		remote = r.RemoteAddr
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(DefaultTransportConfig(), NetworkConfig{LocalAddr: "127.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res := NewHeadProber(client).Probe(context.Background(), srv.URL); !res.OK() {
+		t.Fatalf("%+v", res)
+	}
+	if !strings.HasPrefix(remote, "127.0.0.1:") {
+		t.Errorf("la conexión debía salir de 127.0.0.1 y salió de %s", remote)
+	}
+
+	if _, err := NewClient(DefaultTransportConfig(), NetworkConfig{LocalAddr: "no-es-ip"}); err == nil {
+		t.Error("una dirección local que no es IP debía dar error")
+	}
+}
+
+func TestLoadHostsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts")
+	data := "# hosts de la prueba\n10.0.0.1 api.internal api\n\n10.0.0.2 db.internal # base\n"
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	hosts := map[string]string{}
+	if err := LoadHostsFile(path, hosts); err != nil {
+		t.Fatal(err)
+	}
+	if hosts["api"] != "10.0.0.1" || hosts["api.internal"] != "10.0.0.1" || hosts["db.internal"] != "10.0.0.2" || len(hosts) != 3 {
+		t.Errorf("hosts = %v", hosts)
+	}
+
+	if _, _, err := ParseHostOverride("api.internal=no-es-ip"); err == nil {
+		t.Error("se esperaba un error con una IP inválida")
+	}
+}
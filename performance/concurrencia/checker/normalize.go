@@ -0,0 +1,185 @@
+package checker
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+/*
+	validación y normalización de la lista de urls antes de verificarlos.
+
+	sin este paso un url mal escrito y un sitio caído se reportan igual ("no existe") y un mismo sitio escrito de
+	dos formas distintas se verifica dos veces. cada entrada se normaliza:
+		- se quitan los espacios y se pasa a minúsculas el esquema y el host
+		- un host internacionalizado (IDN, como "ñandú.com.ar") se pasa a su forma ASCII ("xn--and-6ma2c.com.ar")
+		- se quita el fragmento (#...) que no viaja al servidor
+		- se quita el puerto si es el de defecto del esquema (80 para http, 443 para https)
+		- un path "/" es lo mismo que sin path
+	y se rechaza (con un InvalidURLError en lugar de un error de red) si no se puede parsear, si el esquema no es
+	uno de los que se saben verificar o si el host no es un nombre o IP válido. luego de normalizar se descartan
+	los repetidos.
+
+	además de http y https se aceptan los esquemas de los otros tipos de verificación (ver probes.go):
+	tcp://host:puerto, echo://host:puerto, dns://nombre?type=A&expect=1.2.3.4 y grpc://host:puerto?service=nombre
+	(grpcs:// con TLS). tcp, echo y grpc tienen que tener puerto. los nombres de dns:// pueden tener "_" porque
+	así se escriben los registros SRV y TXT de servicios (_servicio._tcp.dominio).
+*/
+
+// InvalidURLError indica que una entrada de la lista no es un url que se pueda verificar
+type InvalidURLError struct {
+	Input  string
+	Reason string
+}
+
+func (e *InvalidURLError) Error() string {
+	// la entrada puede tener usuario y contraseña, la contraseña no se muestra
+	return fmt.Sprintf("invalid input %q: %s", RedactURL(e.Input), e.Reason)
+}
+
+// Invalid indica si el resultado corresponde a una entrada inválida de la lista
+func (r Result) Invalid() bool {
+	var invalid *InvalidURLError
+	return errors.As(r.Err, &invalid)
+}
+
+// PreparedURLs es la lista de urls lista para verificar y lo que se descartó de la original
+type PreparedURLs struct {
+	URLs []string
+	// un resultado por cada entrada inválida, con un InvalidURLError como error
+	Invalid []Result
+	// entradas descartadas porque normalizadas son iguales a una anterior
+	Duplicates []string
+}
+
+// PrepareURLs normaliza, valida y quita los repetidos de una lista de urls, manteniendo el orden original
+func PrepareURLs(inputs []string) PreparedURLs {
+	var prepared PreparedURLs
+	seen := map[string]bool{}
+
+	for _, input := range inputs {
+		normalized, err := Normalize(input)
+		if err != nil {
+			prepared.Invalid = append(prepared.Invalid, Result{URL: input, Err: err})
+			continue
+		}
+		if seen[normalized] {
+			prepared.Duplicates = append(prepared.Duplicates, input)
+			continue
+		}
+		seen[normalized] = true
+		prepared.URLs = append(prepared.URLs, normalized)
+	}
+	return prepared
+}
+
+// Log registra las entradas inválidas (como "invalid input") y las repetidas
+func (p PreparedURLs) Log(logger *slog.Logger) {
+	for _, res := range p.Invalid {
+		logger.Warn("invalid input", append(res.Attrs(), KeyStage, "PrepareURLs")...)
+	}
+	for _, input := range p.Duplicates {
+		logger.Debug("url repetido", KeyURL, RedactURL(input), KeyStage, "PrepareURLs")
+	}
+}
+
+// esquemas que se saben verificar con su puerto por defecto, "" si no tiene y "required" si hay que indicarlo
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+	"tcp":   "required",
+	"echo":  "required",
+	"dns":   "",
+	"grpc":  "required",
+	"grpcs": "required",
+}
+
+// Normalize devuelve la forma normalizada de un url o un InvalidURLError si no es válido
+func Normalize(input string) (string, error) {
+	invalid := func(reason string) (string, error) {
+		return "", &InvalidURLError{Input: input, Reason: reason}
+	}
+
+	raw := strings.TrimSpace(input)
+	if raw == "" {
+		return invalid("url vacío")
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		// el error de url.Parse repite la entrada completa, que ya está en Input
+		var parseErr *url.Error
+		if errors.As(err, &parseErr) {
+			err = parseErr.Err
+		}
+		return invalid(err.Error())
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	defaultPort, ok := defaultPorts[u.Scheme]
+	if !ok {
+		return invalid("el esquema debe ser http, https, tcp, echo, dns, grpc o grpcs")
+	}
+	if u.Opaque != "" || u.Host == "" {
+		return invalid("falta el host")
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if net.ParseIP(host) == nil {
+		if host, err = idna.ToASCII(host); err != nil {
+			return invalid(fmt.Sprintf("host inválido %q: %v", u.Hostname(), err))
+		}
+	}
+	if !validHost(host, u.Scheme == "dns") {
+		return invalid(fmt.Sprintf("host inválido %q", host))
+	}
+	port := u.Port()
+	if port == "" && defaultPort == "required" {
+		return invalid(fmt.Sprintf("falta el puerto, %s necesita host:puerto", u.Scheme))
+	}
+	if port == defaultPort {
+		port = ""
+	}
+	if strings.Contains(host, ":") {
+		// IPv6 va entre corchetes
+		host = "[" + host + "]"
+	}
+	if port != "" {
+		host += ":" + port
+	}
+	u.Host = host
+
+	u.Fragment = ""
+	u.RawFragment = ""
+	if u.Path == "/" && u.RawQuery == "" {
+		u.Path = ""
+	}
+	return u.String(), nil
+}
+
+// validHost acepta IPs y nombres de host con etiquetas de letras, dígitos y guiones, y con underscore "_"
+// además si se pide
+func validHost(host string, underscore bool) bool {
+	if net.ParseIP(host) != nil {
+		return true
+	}
+	if len(host) > 253 {
+		return false
+	}
+	labels := strings.Split(strings.TrimSuffix(host, "."), ".")
+	for _, label := range labels {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '_' && underscore) {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,82 @@
+package checker
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"http://google.com", "http://google.com"},
+		{"  HTTP://Google.COM/  ", "http://google.com"},
+		{"http://google.com:80/", "http://google.com"},
+		{"https://google.com:443/search?q=go#resultados", "https://google.com/search?q=go"},
+		{"http://google.com:8080", "http://google.com:8080"},
+		{"https://[::1]:443/health", "https://[::1]/health"},
+		{"http://127.0.0.1:80", "http://127.0.0.1"},
+		{"TCP://DB.Internal:5432", "tcp://db.internal:5432"},
+		{"dns://google.com?type=A", "dns://google.com?type=A"},
+		{"dns://_xmpp-server._tcp.google.com?type=SRV", "dns://_xmpp-server._tcp.google.com?type=SRV"},
+		{"https://Ñandú.com.ar/", "https://xn--and-6ma2c.com.ar"},
+	}
+	for _, tt := range tests {
+		got, err := Normalize(tt.input)
+		if err != nil {
+			t.Errorf("Normalize(%q) error: %v", tt.input, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Normalize(%q) = %q, se esperaba %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeRejectsMalformedInput(t *testing.T) {
+	for _, input := range []string{
+		"",
+		"google.com",
+		"ftp://google.com",
+		"tcp://db.internal",
+		"http://",
+		"http://goo gle.com",
+		"http://-google.com",
+		"http://google..com",
+		"http://_sip._tcp.google.com",
+		"http://[::1",
+		"mailto:someone@google.com",
+	} {
+		if got, err := Normalize(input); err == nil {
+			t.Errorf("Normalize(%q) = %q, se esperaba un error", input, got)
+		} else if res := (Result{URL: input, Err: err}); !res.Invalid() {
+			t.Errorf("el error de %q debe ser un InvalidURLError: %v", input, err)
+		}
+	}
+}
+
+func TestPrepareURLsRemovesDuplicatesAndKeepsOrder(t *testing.T) {
+	prepared := PrepareURLs([]string{
+		"http://github.com",
+		"http://google.com",
+		"HTTP://GITHUB.COM/",
+		"no es un url",
+		"http://github.com:80#readme",
+		"http://netflix.com",
+	})
+
+	want := []string{"http://github.com", "http://google.com", "http://netflix.com"}
+	if len(prepared.URLs) != len(want) {
+		t.Fatalf("urls = %v, se esperaba %v", prepared.URLs, want)
+	}
+	for i := range want {
+		if prepared.URLs[i] != want[i] {
+			t.Errorf("urls = %v, se esperaba %v", prepared.URLs, want)
+			break
+		}
+	}
+	if len(prepared.Duplicates) != 2 {
+		t.Errorf("repetidos = %v, se esperaban 2", prepared.Duplicates)
+	}
+	if len(prepared.Invalid) != 1 || prepared.Invalid[0].URL != "no es un url" || !prepared.Invalid[0].Invalid() {
+		t.Errorf("inválidos = %+v", prepared.Invalid)
+	}
+}
@@ -0,0 +1,220 @@
+package checker
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Result es el resultado de verificar un url
+type Result struct {
+	URL        string
+	StatusCode int
+	Duration   time.Duration
+	Attempt    int
+	Err        error
+
+	// Throttled es lo que se esperó antes de hacer el HEAD por el rate limiting
+	Throttled time.Duration
+	// Cached indica que el resultado salió de la cache, sin hacer el HEAD
+	Cached bool
+	// Shared indica que el resultado es el de una verificación del mismo url que estaba en vuelo
+	Shared bool
+
+	// Method es el método con el que se verificó: HEAD, o GET si se verificó el contenido o el servidor no aceptaba HEAD
+	Method string
+	// Header son los headers de la respuesta y FinalURL el url que respondió luego de las redirecciones
+	Header   http.Header
+	FinalURL string
+	// Redirects es la cadena de redirecciones que se recibieron hasta llegar a FinalURL
+	Redirects []Redirect
+	// Evaluated indica que se evaluaron los criterios de éxito (ver Expectation) y Unmet tiene los que no se cumplieron
+	Evaluated bool
+	Unmet     []string
+
+	// Answers son las respuestas de una verificación dns://
+	Answers []string
+	// Health es el estado que contestó un servicio gRPC (SERVING, NOT_SERVING, etc.), ver GRPCProber
+	Health string
+	// Links son los links que se encontraron en la página, los completa el prober de páginas del crawler
+	Links []string
+
+	// TLS es lo que se inspeccionó de los certificados si la respuesta (o el handshake que falló) fue https
+	TLS *TLSInfo
+	// Conns son las conexiones que se abrieron y las que se reutilizaron del pool, ver ConnUsage
+	Conns ConnUsage
+}
+
+// OK indica si el url respondió sin error y cumple los criterios de éxito. si no se evaluaron
+// criterios el de los ejemplos originales: responder 200
+func (r Result) OK() bool {
+	if r.Err != nil {
+		return false
+	}
+	if r.Evaluated {
+		return len(r.Unmet) == 0
+	}
+	return r.StatusCode == http.StatusOK
+}
+
+// Attrs devuelve los campos del resultado como pares clave, valor para pasarle al logger,
+// así todas las estrategias registran los resultados con los mismos campos
+func (r Result) Attrs() []any {
+	// los urls pueden tener usuario y contraseña, la contraseña no se registra
+	attrs := []any{KeyURL, RedactURL(r.URL), KeyAttempt, r.Attempt, KeyDuration, r.Duration}
+	if r.Method == http.MethodGet {
+		attrs = append(attrs, KeyMethod, r.Method)
+	}
+	if r.StatusCode != 0 {
+		attrs = append(attrs, KeyStatus, r.StatusCode)
+	}
+	if len(r.Redirects) > 0 {
+		attrs = append(attrs, KeyRedirects, FormatRedirects(r.Redirects))
+	}
+	if r.FinalURL != "" && r.FinalURL != r.URL {
+		attrs = append(attrs, KeyFinalURL, RedactURL(r.FinalURL))
+	}
+	if len(r.Unmet) > 0 {
+		attrs = append(attrs, KeyUnmet, strings.Join(r.Unmet, "; "))
+	}
+	if r.Health != "" {
+		attrs = append(attrs, KeyHealth, r.Health)
+	}
+	if len(r.Answers) > 0 {
+		attrs = append(attrs, KeyAnswers, strings.Join(r.Answers, ","))
+	}
+	if r.TLS != nil {
+		attrs = append(attrs, KeyCertNotAfter, r.TLS.NotAfter())
+		if len(r.TLS.Warnings) > 0 {
+			attrs = append(attrs, KeyCertWarning, strings.Join(r.TLS.Warnings, "; "))
+		}
+	}
+	if r.Throttled > 0 {
+		attrs = append(attrs, KeyThrottled, r.Throttled)
+	}
+	if r.Cached {
+		attrs = append(attrs, KeyCached, true)
+	}
+	if r.Shared {
+		attrs = append(attrs, KeyShared, true)
+	}
+	if r.Err != nil {
+		attrs = append(attrs, KeyErrorKind, r.Kind().String(), KeyError, r.Err)
+	}
+	return attrs
+}
+
+// Prober verifica si un url responde. las tácticas (rate limiting, etc.) se agregan
+// envolviendo un Prober con otro, ver Middleware
+type Prober interface {
+	Probe(ctx context.Context, url string) Result
+}
+
+// ProberFunc permite usar una función como Prober
+type ProberFunc func(ctx context.Context, url string) Result
+
+func (f ProberFunc) Probe(ctx context.Context, url string) Result {
+	return f(ctx, url)
+}
+
+// Middleware envuelve un Prober para agregarle comportamiento antes o después de la verificación
+type Middleware func(Prober) Prober
+
+// Chain envuelve p con los middlewares, el primero queda como el más externo
+func Chain(p Prober, middlewares ...Middleware) Prober {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		p = middlewares[i](p)
+	}
+	return p
+}
+
+// HeadProber es el Prober base, hace HEAD del url como en los ejemplos originales. si los criterios del url
+// piden verificar el contenido hace GET y lee el body, y si el servidor no acepta HEAD (405) reintenta con GET
+type HeadProber struct {
+	Client *http.Client
+	// criterios de éxito de cada url, con nil se sigue usando responder 200
+	Expectations *Expectations
+	// ventana antes del vencimiento de un certificado a partir de la cual se genera una advertencia
+	CertWarnWindow time.Duration
+	// qué redirecciones se siguen, el valor cero sigue hasta 10 como http.Client
+	Redirects RedirectPolicy
+	// headers, autenticación y cookies de los pedidos de cada url, con nil solo se envía el User-Agent
+	Requests *RequestConfigs
+}
+
+func NewHeadProber(client *http.Client) *HeadProber {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &HeadProber{Client: client}
+}
+
+func (p *HeadProber) Probe(ctx context.Context, url string) (res Result) {
+	res = Result{URL: url, Attempt: 1}
+	exp := p.Expectations.For(url)
+	request := p.Requests.For(url)
+
+	// el cliente registra en chain las redirecciones y aplica la política
+	var chain []Redirect
+	client := redirectClient(p.Client, p.Redirects, exp.NoRedirects, &chain)
+
+	res.Method = exp.method()
+	ctx, conns := traceConns(ctx)
+	defer func() { res.Conns = conns() }()
+	start := time.Now()
+	response, body, err := do(ctx, client, request, res.Method, url, exp.maxBodyBytes())
+	if err == nil && res.Method == http.MethodHead && response.StatusCode == http.StatusMethodNotAllowed {
+		// el servidor no acepta HEAD, se reintenta con GET. la duración incluye los dos pedidos
+		res.Method = http.MethodGet
+		chain = nil
+		response, body, err = do(ctx, client, request, res.Method, url, exp.maxBodyBytes())
+	}
+	res.Duration = time.Since(start)
+	res.Redirects = chain
+	if err != nil {
+		res.Err = err
+		if certs, host := tlsFromError(err); len(certs) > 0 {
+			res.TLS = inspectTLS(certs, host, p.CertWarnWindow, time.Now())
+		}
+		return res
+	}
+	if response.TLS != nil {
+		res.TLS = inspectTLS(response.TLS.PeerCertificates, response.Request.URL.Hostname(), p.CertWarnWindow, time.Now())
+	}
+
+	res.StatusCode = response.StatusCode
+	res.Header = response.Header
+	res.FinalURL = response.Request.URL.String()
+	res.Unmet = exp.Check(res, body)
+	res.Evaluated = true
+	return res
+}
+
+// do hace el pedido con la configuración de request y, si es un GET, lee hasta maxBody bytes del body más
+// uno para saber si lo superó
+func do(ctx context.Context, client *http.Client, request *RequestConfig, method, url string, maxBody int64) (*http.Response, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := request.Apply(req); err != nil {
+		return nil, nil, err
+	}
+	response, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	// el body se lee hasta el final para que la conexión vuelva al pool
+	defer DrainAndClose(response.Body)
+
+	if method != http.MethodGet {
+		return response, nil, nil
+	}
+	body, err := io.ReadAll(io.LimitReader(response.Body, maxBody+1))
+	if err != nil {
+		return nil, nil, err
+	}
+	return response, body, nil
+}
@@ -0,0 +1,55 @@
+package checker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHeadProberReportsStatusCode(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead {
+			t.Errorf("método = %s, se esperaba HEAD", r.Method)
+		}
+		if r.URL.Path == "/caido" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	prober := NewHeadProber(server.Client())
+
+	if res := prober.Probe(context.Background(), server.URL); !res.OK() || res.Attempt != 1 {
+		t.Errorf("se esperaba OK en el intento 1: %+v", res)
+	}
+	if res := prober.Probe(context.Background(), server.URL+"/caido"); res.OK() || res.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("se esperaba 503: %+v", res)
+	}
+	if res := prober.Probe(context.Background(), "http://\x7f"); res.Err == nil {
+		t.Errorf("se esperaba error con un url inválido: %+v", res)
+	}
+}
+
+func TestChainAppliesFirstMiddlewareOutermost(t *testing.T) {
+	var calls []string
+	tag := func(name string) Middleware {
+		return func(next Prober) Prober {
+			return ProberFunc(func(ctx context.Context, url string) Result {
+				calls = append(calls, name)
+				return next.Probe(ctx, url)
+			})
+		}
+	}
+	base := ProberFunc(func(ctx context.Context, url string) Result {
+		calls = append(calls, "base")
+		return Result{URL: url}
+	})
+
+	Chain(base, tag("a"), tag("b")).Probe(context.Background(), "http://example.com")
+
+	if got := strings.Join(calls, ","); got != "a,b,base" {
+		t.Errorf("orden de ejecución = %s, se esperaba a,b,base", got)
+	}
+}
@@ -0,0 +1,296 @@
+package checker
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+/*
+	otros tipos de verificación además del HEAD.
+
+	las bases de datos y muchos servicios internos no hablan HTTP. como todas las estrategias verifican a
+	través de un Prober, alcanza con agregar otras implementaciones y elegir cuál usar según el esquema del url,
+	así en una misma lista se pueden mezclar sitios web, puertos y nombres de DNS:
+		- tcp://host:puerto   abre una conexión TCP (TCPProber)
+		- echo://host:puerto  ping/echo a nivel de aplicación: manda PING y espera PONG (EchoProber)
+		- dns://nombre?type=A&expect=1.2.3.4  resuelve el nombre y compara las respuestas (DNSProber)
+
+	el ping/echo es la táctica de disponibilidad: un componente le pregunta a otro si está vivo y espera la
+	respuesta en un tiempo acotado. a diferencia de un tcp:// no alcanza con que el puerto acepte conexiones,
+	el servicio tiene que contestar. ServeEcho es el lado del servicio.
+*/
+
+// DefaultProbeTimeout es el tiempo máximo de una verificación tcp, echo o dns si el context no tiene deadline
+const DefaultProbeTimeout = 5 * time.Second
+
+// Schemes elige el Prober según el esquema del url, así una misma lista puede mezclar tipos de verificación
+type Schemes map[string]Prober
+
+func (s Schemes) Probe(ctx context.Context, rawURL string) Result {
+	scheme, _, _ := strings.Cut(rawURL, "://")
+	p, ok := s[strings.ToLower(scheme)]
+	if !ok {
+		return Result{URL: rawURL, Attempt: 1, Err: &InvalidURLError{Input: rawURL, Reason: fmt.Sprintf("no se sabe verificar el esquema %q", scheme)}}
+	}
+	return p.Probe(ctx, rawURL)
+}
+
+// withTimeout aplica el timeout si el context no tiene uno
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok || timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// ProbeTimeout es el Middleware que limita cada verificación a timeout, así un servidor que acepta la conexión
+// y nunca responde no deja a la verificación esperando para siempre. con 0 no hay límite
+func ProbeTimeout(timeout time.Duration) Middleware {
+	return func(next Prober) Prober {
+		if timeout <= 0 {
+			return next
+		}
+		return ProberFunc(func(ctx context.Context, rawURL string) Result {
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			return next.Probe(ctx, rawURL)
+		})
+	}
+}
+
+// TCPProber verifica que host:puerto acepte conexiones TCP
+type TCPProber struct {
+	Dialer net.Dialer
+	// Dial reemplaza a Dialer si no es nil, ver NetworkConfig
+	Dial    DialFunc
+	Timeout time.Duration
+}
+
+func NewTCPProber() *TCPProber {
+	return &TCPProber{Timeout: DefaultProbeTimeout}
+}
+
+func (p *TCPProber) Probe(ctx context.Context, rawURL string) Result {
+	res := Result{URL: rawURL, Attempt: 1}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+
+	ctx, cancel := withTimeout(ctx, p.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	conn, err := dial(ctx, p.Dial, &p.Dialer, u.Host)
+	res.Duration = time.Since(start)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	conn.Close()
+	res.Evaluated = true
+	return res
+}
+
+// EchoProber hace ping/echo: se conecta a host:puerto, manda "PING <token>" y espera "PONG <token>"
+type EchoProber struct {
+	Dialer net.Dialer
+	// Dial reemplaza a Dialer si no es nil, ver NetworkConfig
+	Dial    DialFunc
+	Timeout time.Duration
+}
+
+func NewEchoProber() *EchoProber {
+	return &EchoProber{Timeout: DefaultProbeTimeout}
+}
+
+// errEchoReply es el error de un servicio que contestó algo distinto de PONG con el mismo token
+var errEchoReply = errors.New("respuesta inesperada al PING")
+
+func (p *EchoProber) Probe(ctx context.Context, rawURL string) (res Result) {
+	res = Result{URL: rawURL, Attempt: 1}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+
+	ctx, cancel := withTimeout(ctx, p.Timeout)
+	defer cancel()
+
+	// la duración es la del ida y vuelta completo, incluyendo la conexión
+	start := time.Now()
+	defer func() { res.Duration = time.Since(start) }()
+
+	conn, err := dial(ctx, p.Dial, &p.Dialer, u.Host)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	defer conn.Close()
+	// el deadline del context también vale para leer y escribir en la conexión
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	token := newToken()
+	if _, err := fmt.Fprintf(conn, "PING %s\n", token); err != nil {
+		res.Err = contextErr(ctx, err)
+		return res
+	}
+	reply, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		res.Err = contextErr(ctx, err)
+		return res
+	}
+	if strings.TrimSpace(reply) != "PONG "+token {
+		res.Err = &ProbeError{Kind: ProtocolError, Err: fmt.Errorf("%w: %q", errEchoReply, strings.TrimSpace(reply))}
+		return res
+	}
+	res.Evaluated = true
+	return res
+}
+
+// dial abre la conexión TCP con fn, o con dialer si fn es nil
+func dial(ctx context.Context, fn DialFunc, dialer *net.Dialer, address string) (net.Conn, error) {
+	if fn != nil {
+		return fn(ctx, "tcp", address)
+	}
+	return dialer.DialContext(ctx, "tcp", address)
+}
+
+// contextErr devuelve el error del context si fue lo que cortó la conexión, así se clasifica como
+// Timeout o Canceled y no como un error de protocolo
+func contextErr(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+func newToken() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// ServeEcho atiende el protocolo ping/echo en ln hasta que se cierre: a cada línea "PING <token>" contesta
+// "PONG <token>". es lo que tiene que agregar un servicio para que se lo pueda verificar con echo://
+func ServeEcho(ln net.Listener) error {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go func() {
+			defer conn.Close()
+			scanner := bufio.NewScanner(conn)
+			for scanner.Scan() {
+				token, ok := strings.CutPrefix(scanner.Text(), "PING ")
+				if !ok {
+					fmt.Fprintln(conn, "ERR se esperaba PING")
+					return
+				}
+				fmt.Fprintf(conn, "PONG %s\n", token)
+			}
+		}()
+	}
+}
+
+// DNSProber resuelve el nombre del url. el parámetro type elige el registro (A, AAAA o CNAME, por defecto A)
+// y expect los valores que tienen que estar entre las respuestas, separados por comas
+type DNSProber struct {
+	Resolver *net.Resolver
+	Timeout  time.Duration
+}
+
+func NewDNSProber() *DNSProber {
+	return &DNSProber{Resolver: net.DefaultResolver, Timeout: DefaultProbeTimeout}
+}
+
+func (p *DNSProber) Probe(ctx context.Context, rawURL string) Result {
+	res := Result{URL: rawURL, Attempt: 1}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	query := u.Query()
+	recordType := strings.ToUpper(query.Get("type"))
+	if recordType == "" {
+		recordType = "A"
+	}
+
+	ctx, cancel := withTimeout(ctx, p.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	res.Answers, err = p.lookup(ctx, recordType, u.Hostname())
+	res.Duration = time.Since(start)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+
+	for _, want := range strings.Split(query.Get("expect"), ",") {
+		want = strings.TrimSpace(want)
+		if want != "" && !containsAnswer(res.Answers, want) {
+			res.Unmet = append(res.Unmet, fmt.Sprintf("%s no incluye %s", recordType, want))
+		}
+	}
+	res.Evaluated = true
+	return res
+}
+
+func (p *DNSProber) lookup(ctx context.Context, recordType, name string) ([]string, error) {
+	switch recordType {
+	case "A", "AAAA":
+		network := "ip4"
+		if recordType == "AAAA" {
+			network = "ip6"
+		}
+		ips, err := p.Resolver.LookupIP(ctx, network, name)
+		if err != nil {
+			return nil, err
+		}
+		answers := make([]string, len(ips))
+		for i, ip := range ips {
+			answers[i] = ip.String()
+		}
+		sort.Strings(answers)
+		return answers, nil
+	case "CNAME":
+		cname, err := p.Resolver.LookupCNAME(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		return []string{cname}, nil
+	}
+	return nil, &InvalidURLError{Input: name, Reason: fmt.Sprintf("tipo de registro %q no soportado, debe ser A, AAAA o CNAME", recordType)}
+}
+
+// containsAnswer compara sin importar mayúsculas ni el punto final de los nombres
+func containsAnswer(answers []string, want string) bool {
+	want = strings.TrimSuffix(strings.ToLower(want), ".")
+	for _, a := range answers {
+		if strings.TrimSuffix(strings.ToLower(a), ".") == want {
+			return true
+		}
+	}
+	return false
+}
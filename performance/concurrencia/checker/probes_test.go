@@ -0,0 +1,140 @@
+package checker
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// listen abre un listener local que se cierra al terminar el test
+func listen(t *testing.T) net.Listener {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	return ln
+}
+
+func TestTCPProber(t *testing.T) {
+	ln := listen(t)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	closed := listen(t)
+	closed.Close()
+
+	prober := NewTCPProber()
+	if res := prober.Probe(context.Background(), "tcp://"+ln.Addr().String()); !res.OK() {
+		t.Errorf("se esperaba que el puerto acepte la conexión: %+v", res)
+	}
+	if res := prober.Probe(context.Background(), "tcp://"+closed.Addr().String()); res.Kind() != ConnectRefused {
+		t.Errorf("categoría = %s, se esperaba connect_refused", res.Kind())
+	}
+}
+
+func TestEchoProber(t *testing.T) {
+	echo := listen(t)
+	go ServeEcho(echo)
+
+	// acepta la conexión pero contesta cualquier cosa
+	wrong := listen(t)
+	go func() {
+		for {
+			conn, err := wrong.Accept()
+			if err != nil {
+				return
+			}
+			fmt.Fprintln(conn, "HOLA")
+			conn.Close()
+		}
+	}()
+
+	// acepta la conexión y nunca contesta, como un servicio colgado
+	hung := listen(t)
+	go func() {
+		for {
+			if _, err := hung.Accept(); err != nil {
+				return
+			}
+		}
+	}()
+
+	prober := NewEchoProber()
+	if res := prober.Probe(context.Background(), "echo://"+echo.Addr().String()); !res.OK() || res.Duration == 0 {
+		t.Errorf("se esperaba PONG: %+v", res)
+	}
+	if res := prober.Probe(context.Background(), "echo://"+wrong.Addr().String()); res.Kind() != ProtocolError {
+		t.Errorf("categoría = %s, se esperaba protocol (%v)", res.Kind(), res.Err)
+	}
+
+	prober.Timeout = 50 * time.Millisecond
+	if res := prober.Probe(context.Background(), "echo://"+hung.Addr().String()); res.Kind() != Timeout {
+		t.Errorf("categoría = %s, se esperaba timeout (%v)", res.Kind(), res.Err)
+	}
+}
+
+func TestDNSProber(t *testing.T) {
+	prober := NewDNSProber()
+
+	res := prober.Probe(context.Background(), "dns://localhost?type=A&expect=127.0.0.1")
+	if !res.OK() || len(res.Answers) == 0 {
+		t.Errorf("localhost debía resolver a 127.0.0.1: %+v", res)
+	}
+	res = prober.Probe(context.Background(), "dns://localhost?type=A&expect=10.0.0.1")
+	if res.OK() || !strings.Contains(strings.Join(res.Unmet, ";"), "10.0.0.1") {
+		t.Errorf("se esperaba que falte 10.0.0.1 entre las respuestas: %+v", res)
+	}
+	if res := prober.Probe(context.Background(), "dns://no-existe.invalid"); res.Kind() != DNSError {
+		t.Errorf("categoría = %s, se esperaba dns (%v)", res.Kind(), res.Err)
+	}
+	if res := prober.Probe(context.Background(), "dns://localhost?type=MX"); !res.Invalid() {
+		t.Errorf("un tipo de registro no soportado es un invalid input: %v", res.Err)
+	}
+}
+
+func TestSchemesMixesProbeTypes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	echo := listen(t)
+	go ServeEcho(echo)
+
+	// el inventario mezcla tipos de verificación y pasa por PrepareURLs como cualquier lista
+	path := filepath.Join(t.TempDir(), "inventario.txt")
+	data := "# servicios\n" + server.URL + "\n\ntcp://" + echo.Addr().String() + "\necho://" + echo.Addr().String() + "\ndns://localhost\n"
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	urls, err := (&Inventory{Path: path}).URLs(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prepared := PrepareURLs(urls)
+	if len(prepared.URLs) != 4 || len(prepared.Invalid) != 0 {
+		t.Fatalf("inventario preparado = %+v", prepared)
+	}
+
+	prober := Schemes{"http": NewHeadProber(nil), "tcp": NewTCPProber(), "echo": NewEchoProber(), "dns": NewDNSProber()}
+	for _, url := range prepared.URLs {
+		if res := prober.Probe(context.Background(), url); !res.OK() {
+			t.Errorf("%s: %+v", url, res)
+		}
+	}
+	if res := prober.Probe(context.Background(), "ftp://localhost:21"); !res.Invalid() {
+		t.Errorf("un esquema sin Prober es un invalid input: %v", res.Err)
+	}
+}
@@ -0,0 +1,219 @@
+package checker
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	httppprof "net/http/pprof"
+	"os"
+	"os/signal"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+	"syscall"
+	"time"
+)
+
+// ProfileConfig son los flags para perfilar una ejecución con las herramientas de go: los perfiles de CPU, heap,
+// bloqueos y mutex se ven con go tool pprof y la traza de ejecución (cuándo corre cada gorutina, en qué P y
+// cuánto espera en cada canal) con go tool trace
+type ProfileConfig struct {
+	CPU   string
+	Heap  string
+	Block string
+	Mutex string
+	Trace string
+	// dirección donde se sirven los endpoints de net/http/pprof, por ejemplo localhost:6060
+	Addr string
+	// con Daemon mayor a 0 la verificación se repite con ese intervalo hasta que se cancela, ver Loop
+	Daemon time.Duration
+}
+
+// RegisterProfileFlags agrega al FlagSet los flags -cpuprofile, -memprofile, -blockprofile, -mutexprofile,
+// -exectrace, -pprof y -daemon
+func RegisterProfileFlags(fs *flag.FlagSet) *ProfileConfig {
+	cfg := &ProfileConfig{}
+	fs.StringVar(&cfg.CPU, "cpuprofile", "", "archivo donde guardar el perfil de CPU de la ejecución")
+	fs.StringVar(&cfg.Heap, "memprofile", "", "archivo donde guardar el perfil de memoria (heap) al terminar")
+	fs.StringVar(&cfg.Block, "blockprofile", "", "archivo donde guardar el perfil de bloqueos (canales, select, WaitGroup)")
+	fs.StringVar(&cfg.Mutex, "mutexprofile", "", "archivo donde guardar el perfil de contención de los mutex")
+	fs.StringVar(&cfg.Trace, "exectrace", "", "archivo donde guardar la traza de ejecución para go tool trace")
+	fs.StringVar(&cfg.Addr, "pprof", "", "dirección donde servir /debug/pprof/, por ejemplo localhost:6060")
+	fs.DurationVar(&cfg.Daemon, "daemon", 0, "repetir la verificación con este intervalo hasta ctrl-c (0 para verificar una vez)")
+	return cfg
+}
+
+// Profiler es la sesión de profiling de una ejecución, Stop guarda los perfiles
+type Profiler struct {
+	cfg    *ProfileConfig
+	logger *slog.Logger
+	cpu    *os.File
+	trace  *os.File
+	server *http.Server
+	addr   net.Addr
+	quit   chan os.Signal
+	done   chan struct{}
+}
+
+// Start comienza los perfiles configurados y el servidor de pprof. además, mientras dura la sesión, la señal
+// SIGQUIT (ctrl-\ o kill -QUIT) escribe en stderr el stack de todas las gorutinas y la ejecución sigue: sirve
+// para ver dónde está bloqueado un pipeline que no termina
+func (cfg *ProfileConfig) Start(logger *slog.Logger) (p *Profiler, err error) {
+	p = &Profiler{cfg: cfg, logger: logger, quit: make(chan os.Signal, 1), done: make(chan struct{})}
+	defer func() {
+		if err != nil {
+			p.stopRunning()
+		}
+	}()
+
+	// los perfiles de bloqueos y mutex no registran nada hasta que se les indica cada cuánto muestrear.
+	// 1 registra todos los eventos, que para un ejemplo está bien pero en producción es caro
+	if cfg.Block != "" || cfg.Addr != "" {
+		runtime.SetBlockProfileRate(1)
+	}
+	if cfg.Mutex != "" || cfg.Addr != "" {
+		runtime.SetMutexProfileFraction(1)
+	}
+
+	if cfg.CPU != "" {
+		if p.cpu, err = os.Create(cfg.CPU); err != nil {
+			return p, err
+		}
+		if err = pprof.StartCPUProfile(p.cpu); err != nil {
+			return p, fmt.Errorf("no se pudo comenzar el perfil de CPU: %w", err)
+		}
+	}
+	if cfg.Trace != "" {
+		if p.trace, err = os.Create(cfg.Trace); err != nil {
+			return p, err
+		}
+		if err = trace.Start(p.trace); err != nil {
+			return p, fmt.Errorf("no se pudo comenzar la traza de ejecución: %w", err)
+		}
+	}
+
+	if cfg.Addr != "" {
+		listener, err := net.Listen("tcp", cfg.Addr)
+		if err != nil {
+			return p, fmt.Errorf("no se pudo servir pprof: %w", err)
+		}
+		mux := http.NewServeMux()
+		mux.HandleFunc("/debug/pprof/", httppprof.Index)
+		mux.HandleFunc("/debug/pprof/cmdline", httppprof.Cmdline)
+		mux.HandleFunc("/debug/pprof/profile", httppprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", httppprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", httppprof.Trace)
+		p.server = &http.Server{Handler: mux}
+		p.addr = listener.Addr()
+		go p.server.Serve(listener)
+		logger.Info("pprof disponible", "address", "http://"+p.addr.String()+"/debug/pprof/")
+	}
+
+	signal.Notify(p.quit, syscall.SIGQUIT)
+	go p.dumpOnQuit()
+	return p, nil
+}
+
+// dumpOnQuit escribe el stack de todas las gorutinas cada vez que llega SIGQUIT
+func (p *Profiler) dumpOnQuit() {
+	for {
+		select {
+		case <-p.quit:
+			p.logger.Info("stack de las gorutinas", "goroutines", runtime.NumGoroutine())
+			pprof.Lookup("goroutine").WriteTo(os.Stderr, 2)
+		case <-p.done:
+			return
+		}
+	}
+}
+
+// Addr devuelve la dirección donde se sirve pprof, nil si no se indicó -pprof
+func (p *Profiler) Addr() net.Addr {
+	return p.addr
+}
+
+// Stop termina los perfiles que estaban corriendo, guarda los de heap, bloqueos y mutex y deja de servir pprof.
+// SIGQUIT vuelve a su comportamiento por defecto (termina el proceso mostrando los stacks)
+func (p *Profiler) Stop() error {
+	select {
+	case <-p.done:
+		return nil
+	default:
+	}
+	errs := p.stopRunning()
+
+	// el heap se toma después de un GC para que muestre lo que sigue vivo y no la basura pendiente
+	runtime.GC()
+	for _, profile := range []struct{ name, path string }{
+		{"heap", p.cfg.Heap}, {"block", p.cfg.Block}, {"mutex", p.cfg.Mutex},
+	} {
+		if profile.path == "" {
+			continue
+		}
+		f, err := os.Create(profile.path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		errs = append(errs, p.saved(f, pprof.Lookup(profile.name).WriteTo(f, 0)))
+	}
+	return errors.Join(errs...)
+}
+
+// stopRunning termina lo que quedó corriendo desde Start: la atención de SIGQUIT, los perfiles de CPU y la
+// traza y el servidor de pprof
+func (p *Profiler) stopRunning() []error {
+	signal.Stop(p.quit)
+	close(p.done)
+
+	var errs []error
+	if p.cpu != nil {
+		pprof.StopCPUProfile()
+		errs = append(errs, p.saved(p.cpu, nil))
+	}
+	if p.trace != nil {
+		trace.Stop()
+		errs = append(errs, p.saved(p.trace, nil))
+	}
+	if p.server != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		errs = append(errs, p.server.Shutdown(ctx))
+	}
+	return errs
+}
+
+// saved cierra el archivo de un perfil y registra dónde quedó
+func (p *Profiler) saved(f *os.File, err error) error {
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("no se pudo guardar %s: %w", f.Name(), err)
+	}
+	p.logger.Info("perfil guardado", "file", f.Name())
+	return nil
+}
+
+// Loop ejecuta run una vez o, en modo daemon, cada cfg.Daemon hasta que se cancela ctx. el modo daemon sirve
+// para tomar perfiles con -pprof mientras el ejemplo está verificando
+func (cfg *ProfileConfig) Loop(ctx context.Context, run func()) {
+	run()
+	if cfg.Daemon <= 0 {
+		return
+	}
+	ticker := time.NewTicker(cfg.Daemon)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			run()
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package checker
+
+import (
+	"context"
+	"flag"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestProfilerWritesEveryProfile(t *testing.T) {
+	dir := t.TempDir()
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	cfg := RegisterProfileFlags(fs)
+	args := []string{"-pprof=127.0.0.1:0"}
+	for _, name := range []string{"cpuprofile", "memprofile", "blockprofile", "mutexprofile", "exectrace"} {
+		args = append(args, "-"+name+"="+filepath.Join(dir, name))
+	}
+	if err := fs.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+
+	profiler, err := cfg.Start(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := http.Get("http://" + profiler.Addr().String() + "/debug/pprof/goroutine?debug=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(body), "goroutine profile") {
+		t.Errorf("/debug/pprof/goroutine respondió %d: %.200s", resp.StatusCode, body)
+	}
+
+	if err := profiler.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"cpuprofile", "memprofile", "blockprofile", "mutexprofile", "exectrace"} {
+		info, err := os.Stat(filepath.Join(dir, name))
+		if err != nil || info.Size() == 0 {
+			t.Errorf("%s no se guardó (%v)", name, err)
+		}
+	}
+	if _, err := http.Get("http://" + profiler.Addr().String() + "/debug/pprof/"); err == nil {
+		t.Error("pprof se sigue sirviendo después de Stop")
+	}
+}
+
+func TestProfilerStartFailsWithBadPath(t *testing.T) {
+	cfg := &ProfileConfig{CPU: filepath.Join(t.TempDir(), "no", "existe", "cpu.out")}
+	if _, err := cfg.Start(slog.New(slog.NewTextHandler(io.Discard, nil))); err == nil {
+		t.Fatal("se esperaba error con un directorio que no existe")
+	}
+	// el perfil de CPU no quedó tomado y se puede volver a comenzar
+	profiler, err := (&ProfileConfig{CPU: filepath.Join(t.TempDir(), "cpu.out")}).Start(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	profiler.Stop()
+}
+
+func TestLoopRepeatsInDaemonMode(t *testing.T) {
+	var runs atomic.Int32
+	(&ProfileConfig{}).Loop(context.Background(), func() { runs.Add(1) })
+	if runs.Load() != 1 {
+		t.Fatalf("sin -daemon se ejecutó %d veces", runs.Load())
+	}
+
+	runs.Store(0)
+	ctx, cancel := context.WithTimeout(context.Background(), 55*time.Millisecond)
+	defer cancel()
+	(&ProfileConfig{Daemon: 10 * time.Millisecond}).Loop(ctx, func() { runs.Add(1) })
+	if runs.Load() < 3 {
+		t.Errorf("en modo daemon se ejecutó %d veces en 55ms", runs.Load())
+	}
+}
@@ -0,0 +1,201 @@
+package checker
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+	control de la cantidad de pedidos (táctica de performance "manage work requests" aplicada del lado del cliente).
+
+	con muchos urls del mismo host las estrategias concurrentes le pegan a ese host con tantos HEAD en paralelo
+	como gorutinas tengan, lo que alcanza para que nos bloqueen. el Limiter aplica tres límites:
+		- un token bucket global: cantidad de pedidos por segundo entre todos los hosts
+		- un token bucket por host: cantidad de pedidos por segundo a un mismo host
+		- un máximo de conexiones concurrentes por host (un semáforo por host)
+
+	un token bucket se llena a razón de rate tokens por segundo hasta un máximo de burst, cada pedido consume
+	un token y si no hay espera a que se genere.
+*/
+
+// LimitConfig es la configuración del Limiter, un valor 0 significa sin límite
+type LimitConfig struct {
+	// pedidos por segundo entre todos los hosts y ráfaga máxima
+	Rate  float64
+	Burst int
+
+	// pedidos por segundo a un mismo host y ráfaga máxima
+	HostRate  float64
+	HostBurst int
+
+	// máximo de HEAD concurrentes a un mismo host
+	MaxConnsPerHost int
+}
+
+// Limiter aplica los límites globales y por host antes de cada pedido
+type Limiter struct {
+	cfg    LimitConfig
+	global *tokenBucket
+
+	mu    sync.Mutex
+	hosts map[string]*hostLimit
+}
+
+type hostLimit struct {
+	bucket *tokenBucket
+	conns  chan struct{}
+}
+
+func NewLimiter(cfg LimitConfig) *Limiter {
+	return &Limiter{
+		cfg:    cfg,
+		global: newTokenBucket(cfg.Rate, cfg.Burst),
+		hosts:  map[string]*hostLimit{},
+	}
+}
+
+// Acquire espera a que el pedido al host respete todos los límites. si no hay error el que llama
+// debe ejecutar release cuando termina el pedido para liberar la conexión del host
+func (l *Limiter) Acquire(ctx context.Context, host string) (release func(), err error) {
+	h := l.host(host)
+
+	if h.conns != nil {
+		select {
+		case h.conns <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	release = func() {
+		if h.conns != nil {
+			<-h.conns
+		}
+	}
+
+	if err := h.bucket.wait(ctx); err != nil {
+		release()
+		return nil, err
+	}
+	if err := l.global.wait(ctx); err != nil {
+		// el pedido no se hace, el token del host se devuelve para no frenar a los que siguen
+		h.bucket.cancel()
+		release()
+		return nil, err
+	}
+	return release, nil
+}
+
+func (l *Limiter) host(host string) *hostLimit {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	h, ok := l.hosts[host]
+	if !ok {
+		h = &hostLimit{bucket: newTokenBucket(l.cfg.HostRate, l.cfg.HostBurst)}
+		if l.cfg.MaxConnsPerHost > 0 {
+			h.conns = make(chan struct{}, l.cfg.MaxConnsPerHost)
+		}
+		l.hosts[host] = h
+	}
+	return h
+}
+
+// RateLimit es el Middleware que hace pasar cada verificación por el Limiter
+func RateLimit(l *Limiter) Middleware {
+	return func(next Prober) Prober {
+		return ProberFunc(func(ctx context.Context, rawURL string) Result {
+			start := time.Now()
+			release, err := l.Acquire(ctx, Host(rawURL))
+			if err != nil {
+				return Result{URL: rawURL, Attempt: 1, Err: err, Throttled: time.Since(start)}
+			}
+			defer release()
+
+			throttled := time.Since(start)
+			res := next.Probe(ctx, rawURL)
+			res.Throttled += throttled
+			return res
+		})
+	}
+}
+
+// Host devuelve el host del url en minúsculas y sin el puerto, o el url entero si no se puede parsear
+func Host(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+// tokenBucket nil significa sin límite
+type tokenBucket struct {
+	rate  float64
+	burst float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if rate <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// reserve consume un token y devuelve cuánto hay que esperar para que ese token esté disponible
+func (b *tokenBucket) reserve(now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// cancel devuelve un token reservado que no se llegó a usar
+func (b *tokenBucket) cancel() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	b.tokens++
+	b.mu.Unlock()
+}
+
+func (b *tokenBucket) wait(ctx context.Context) error {
+	if b == nil {
+		return nil
+	}
+
+	delay := b.reserve(time.Now())
+	if delay == 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		b.cancel()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,104 @@
+package checker
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTokenBucketSpacesRequestsAfterBurst(t *testing.T) {
+	b := newTokenBucket(10, 2)
+	now := time.Now()
+	b.last = now
+
+	if d := b.reserve(now); d != 0 {
+		t.Errorf("el primer pedido de la ráfaga no debe esperar, espera %v", d)
+	}
+	if d := b.reserve(now); d != 0 {
+		t.Errorf("el segundo pedido de la ráfaga no debe esperar, espera %v", d)
+	}
+	if d := b.reserve(now); d != 100*time.Millisecond {
+		t.Errorf("con 10 pedidos por segundo el tercero debe esperar 100ms, espera %v", d)
+	}
+	if d := b.reserve(now.Add(100 * time.Millisecond)); d != 100*time.Millisecond {
+		t.Errorf("el cuarto debe esperar otros 100ms, espera %v", d)
+	}
+}
+
+func TestLimiterBoundsConcurrentRequestsPerHost(t *testing.T) {
+	limiter := NewLimiter(LimitConfig{MaxConnsPerHost: 2})
+
+	var current, max int32
+	prober := RateLimit(limiter)(ProberFunc(func(ctx context.Context, url string) Result {
+		n := atomic.AddInt32(&current, 1)
+		for {
+			m := atomic.LoadInt32(&max)
+			if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&current, -1)
+		return Result{URL: url}
+	}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			prober.Probe(context.Background(), "http://Example.com:8080/path")
+		}()
+	}
+	wg.Wait()
+
+	if max != 2 {
+		t.Errorf("máximo de pedidos concurrentes al host = %d, se esperaba 2", max)
+	}
+}
+
+func TestRateLimitReturnsContextError(t *testing.T) {
+	limiter := NewLimiter(LimitConfig{HostRate: 0.001})
+	prober := RateLimit(limiter)(ProberFunc(func(ctx context.Context, url string) Result {
+		return Result{URL: url, StatusCode: 200}
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if res := prober.Probe(ctx, "http://example.com"); !res.OK() {
+		t.Fatalf("el primer pedido usa la ráfaga y no debe esperar: %+v", res)
+	}
+	res := prober.Probe(ctx, "http://example.com")
+	if res.Err != context.DeadlineExceeded {
+		t.Errorf("se esperaba DeadlineExceeded esperando el token, se obtuvo %+v", res)
+	}
+	if res.Throttled <= 0 {
+		t.Errorf("se debe registrar el tiempo esperado: %+v", res)
+	}
+}
+
+func TestHostNormalizesCaseAndPort(t *testing.T) {
+	if h := Host("http://Example.COM:8080/x"); h != "example.com" {
+		t.Errorf("Host = %s", h)
+	}
+}
+
+// si se cancela esperando el límite global el token del host se devuelve
+func TestLimiterRefundsHostTokenWhenGlobalWaitFails(t *testing.T) {
+	limiter := NewLimiter(LimitConfig{Rate: 0.001, HostRate: 0.001})
+	if _, err := limiter.Acquire(context.Background(), "a.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := limiter.Acquire(ctx, "b.com"); err != context.DeadlineExceeded {
+		t.Fatalf("se esperaba DeadlineExceeded esperando el límite global, se obtuvo %v", err)
+	}
+	if d := limiter.host("b.com").bucket.reserve(time.Now()); d != 0 {
+		t.Errorf("el token de b.com no se devolvió, el siguiente pedido espera %v", d)
+	}
+}
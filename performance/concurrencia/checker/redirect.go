@@ -0,0 +1,116 @@
+package checker
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+/*
+	seguimiento de las redirecciones y política de redirecciones.
+
+	http.Head sigue las redirecciones sin decir nada, así "http://google.com" resulta OK y no se ve el salto a
+	https y a www. el HeadProber registra en Result.Redirects cada respuesta 3xx que recibió (url, status y a
+	dónde redirige) y el url final queda en Result.FinalURL.
+
+	la RedirectPolicy decide qué redirecciones se siguen:
+		- hasta Max saltos (por defecto 10, como http.Client), o ninguno con None
+		- con FailCrossDomain falla si una redirección lleva a otro dominio
+		- con FailDowngrade falla si una redirección pasa de https a http
+*/
+
+// Redirect es un salto de la cadena de redirecciones
+type Redirect struct {
+	URL        string `json:"url"`
+	StatusCode int    `json:"status_code"`
+	Location   string `json:"location"`
+}
+
+// FormatRedirects arma la cadena como texto para los logs: "http://a.com (301) -> https://a.com (302) -> https://www.a.com"
+func FormatRedirects(chain []Redirect) string {
+	var b strings.Builder
+	for _, r := range chain {
+		fmt.Fprintf(&b, "%s (%d) -> ", r.URL, r.StatusCode)
+	}
+	if len(chain) > 0 {
+		b.WriteString(chain[len(chain)-1].Location)
+	}
+	return b.String()
+}
+
+// máximo de redirecciones con Max 0, el mismo que usa http.Client
+const defaultMaxRedirects = 10
+
+// RedirectPolicy es la política de redirecciones del HeadProber, el valor cero sigue hasta 10 como http.Client
+type RedirectPolicy struct {
+	// máximo de redirecciones que se siguen, 0 para el defecto
+	Max int
+	// no seguir ninguna redirección, el 3xx es la respuesta que se evalúa
+	None bool
+	// fallar si una redirección lleva a otro dominio
+	FailCrossDomain bool
+	// fallar si una redirección pasa de https a http
+	FailDowngrade bool
+}
+
+// RedirectPolicyError es el error de una redirección que la política no permite
+type RedirectPolicyError struct {
+	From, To string
+	Reason   string
+}
+
+func (e *RedirectPolicyError) Error() string {
+	return fmt.Sprintf("redirección no permitida de %s a %s: %s", e.From, e.To, e.Reason)
+}
+
+// check decide si se sigue la redirección de from a to, hop es el número de esta redirección en la cadena
+func (p RedirectPolicy) check(from, to *url.URL, hop int) error {
+	max := p.Max
+	if max <= 0 {
+		max = defaultMaxRedirects
+	}
+	if hop > max {
+		return fmt.Errorf("%w: el máximo es %d", ErrTooManyRedirects, max)
+	}
+	if p.FailDowngrade && from.Scheme == "https" && to.Scheme == "http" {
+		return &RedirectPolicyError{From: from.String(), To: to.String(), Reason: "pasa de https a http"}
+	}
+	if p.FailCrossDomain && domain(from.Hostname()) != domain(to.Hostname()) {
+		return &RedirectPolicyError{From: from.String(), To: to.String(), Reason: "lleva a otro dominio"}
+	}
+	return nil
+}
+
+// domain devuelve el dominio registrable del host según la lista de sufijos públicos, así www.google.com y
+// google.com son el mismo dominio y también www.ort.edu.uy y ort.edu.uy, pero a.co.uk y b.co.uk no lo son.
+// las IPs y los nombres sin sufijo público (localhost) son su propio dominio
+func domain(host string) string {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if net.ParseIP(host) != nil {
+		return host
+	}
+	registrable, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return host
+	}
+	return registrable
+}
+
+// redirectClient devuelve una copia del cliente (comparte el Transport) que registra en chain cada
+// redirección y aplica la política. con noRedirects devuelve el primer 3xx en lugar de seguirlo
+func redirectClient(client *http.Client, policy RedirectPolicy, noRedirects bool, chain *[]Redirect) *http.Client {
+	c := *client
+	c.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		from := via[len(via)-1].URL
+		*chain = append(*chain, Redirect{URL: from.String(), StatusCode: req.Response.StatusCode, Location: req.URL.String()})
+		if noRedirects || policy.None {
+			return http.ErrUseLastResponse
+		}
+		return policy.check(from, req.URL, len(via))
+	}
+	return &c
+}
@@ -0,0 +1,97 @@
+package checker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHeadProberRecordsRedirectChain(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/a", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/a", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/b", http.StatusFound)
+	})
+	mux.HandleFunc("/b", func(w http.ResponseWriter, r *http.Request) {})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	prober := NewHeadProber(server.Client())
+
+	res := prober.Probe(context.Background(), server.URL+"/")
+	if !res.OK() || res.FinalURL != server.URL+"/b" {
+		t.Fatalf("se esperaba llegar a /b: %+v", res)
+	}
+	want := server.URL + "/ (301) -> " + server.URL + "/a (302) -> " + server.URL + "/b"
+	if got := FormatRedirects(res.Redirects); got != want {
+		t.Errorf("cadena = %s, se esperaba %s", got, want)
+	}
+
+	prober.Redirects = RedirectPolicy{Max: 1}
+	if res := prober.Probe(context.Background(), server.URL+"/"); res.Kind() != TooManyRedirects || len(res.Redirects) != 2 {
+		t.Errorf("con Max 1 se esperaba too_many_redirects: %v %+v", res.Err, res.Redirects)
+	}
+
+	prober.Redirects = RedirectPolicy{None: true}
+	res = prober.Probe(context.Background(), server.URL+"/")
+	if res.Err != nil || res.StatusCode != http.StatusMovedPermanently || len(res.Redirects) != 1 {
+		t.Errorf("con None se esperaba el 301 como respuesta: %+v", res)
+	}
+}
+
+func TestRedirectPolicyFailsOnDowngradeAndCrossDomain(t *testing.T) {
+	plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer plain.Close()
+	secure := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, plain.URL, http.StatusFound)
+	}))
+	defer secure.Close()
+
+	prober := NewHeadProber(secure.Client())
+	if res := prober.Probe(context.Background(), secure.URL); !res.OK() {
+		t.Fatalf("sin política el downgrade se sigue: %+v", res)
+	}
+
+	prober.Redirects = RedirectPolicy{FailDowngrade: true}
+	res := prober.Probe(context.Background(), secure.URL)
+	if res.Kind() != RedirectNotAllowed || !strings.Contains(res.Err.Error(), "https a http") {
+		t.Errorf("se esperaba que falle el downgrade: %v", res.Err)
+	}
+
+	// 127.0.0.1 y localhost son dominios distintos
+	cross := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, strings.Replace(plain.URL, "127.0.0.1", "localhost", 1), http.StatusFound)
+	}))
+	defer cross.Close()
+
+	prober = NewHeadProber(cross.Client())
+	prober.Redirects = RedirectPolicy{FailCrossDomain: true}
+	if res := prober.Probe(context.Background(), cross.URL); res.Kind() != RedirectNotAllowed {
+		t.Errorf("se esperaba que falle la redirección a otro dominio: %v", res.Err)
+	}
+}
+
+func TestDomain(t *testing.T) {
+	for host, want := range map[string]string{
+		"www.google.com": "google.com",
+		"Google.com.":    "google.com",
+		"localhost":      "localhost",
+		"127.0.0.1":      "127.0.0.1",
+		"www.ort.edu.uy": "ort.edu.uy",
+		"fing.edu.uy":    "fing.edu.uy",
+		"a.co.uk":        "a.co.uk",
+		"www.b.co.uk":    "b.co.uk",
+	} {
+		if got := domain(host); got != want {
+			t.Errorf("domain(%q) = %q, se esperaba %q", host, got, want)
+		}
+	}
+	// con las dos últimas etiquetas serían el mismo dominio (edu.uy y co.uk)
+	if domain("www.ort.edu.uy") == domain("fing.edu.uy") || domain("a.co.uk") == domain("b.co.uk") {
+		t.Error("dos sitios bajo un sufijo público no son el mismo dominio")
+	}
+}
@@ -0,0 +1,314 @@
+package checker
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+	respeto de robots.txt.
+
+	para poder apuntar el checker o el crawler a sitios de terceros hay que respetar lo que el sitio pide en su
+	robots.txt (RFC 9309): qué caminos no se deben pedir (Disallow y Allow) y cuánto esperar entre pedidos
+	(Crawl-delay, que no es parte del RFC pero lo usan muchos sitios).
+
+	el archivo se busca una sola vez por esquema y host. de sus grupos se usa el del User-agent que es igual,
+	sin distinguir mayúsculas, al nombre de producto de nuestro agente (UserAgent hasta la "/" de la versión) o
+	si no hay el de "*". dentro del grupo gana la regla más larga que coincide con el camino y con el mismo
+	largo gana Allow. los urls no permitidos quedan como no verificados (NotChecked) y el Crawl-delay se aplica
+	con un token bucket por host de un pedido cada delay.
+
+	si el sitio no tiene robots.txt (4xx) se permite todo. si responde 5xx o no se lo puede descargar el RFC
+	pide asumir que no se permite nada: los urls del sitio quedan no verificados y el robots.txt se vuelve a
+	pedir pasado RobotsRetry, así un sitio caído no queda bloqueado durante toda la ejecución.
+*/
+
+// UserAgent es el nombre con el que el checker se identifica ante los sitios y con el que busca su grupo en robots.txt
+const UserAgent = "tacticas-arq-go"
+
+// tamaño máximo de robots.txt que se lee, el RFC pide leer al menos 500 KiB
+const maxRobotsBytes = 500 << 10
+
+// ErrRobotsDisallowed es el error de los resultados de urls que el robots.txt del sitio no permite pedir
+var ErrRobotsDisallowed = &SkipError{Reason: "robots.txt"}
+
+// ErrRobotsUnreachable es el error de los resultados de urls de un sitio cuyo robots.txt respondió 5xx o no
+// se pudo descargar
+var ErrRobotsUnreachable = &SkipError{Reason: "robots.txt unreachable"}
+
+// RobotsRetry es cuánto se espera para volver a pedir un robots.txt que no se pudo descargar
+const RobotsRetry = time.Minute
+
+// RobotsRules son las reglas de robots.txt que aplican a un agente
+type RobotsRules struct {
+	rules []robotsRule
+	// CrawlDelay es el tiempo mínimo entre pedidos al sitio, 0 si no lo indica
+	CrawlDelay time.Duration
+	// unreachable indica que el robots.txt no se pudo descargar y no se permite nada
+	unreachable bool
+}
+
+type robotsRule struct {
+	pattern string
+	match   *regexp.Regexp
+	allow   bool
+}
+
+// ParseRobots lee un robots.txt y devuelve las reglas del grupo de agent, o las del grupo "*" si no hay uno
+func ParseRobots(r io.Reader, agent string) (*RobotsRules, error) {
+	agent = productToken(agent)
+	var (
+		specific, generic *RobotsRules
+		// agentes del grupo que se está leyendo, un User-agent luego de una regla empieza otro grupo
+		agents  []string
+		inRules bool
+	)
+
+	scanner := bufio.NewScanner(io.LimitReader(r, maxRobotsBytes))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		if key == "user-agent" {
+			if inRules {
+				agents, inRules = nil, false
+			}
+			agents = append(agents, productToken(value))
+			continue
+		}
+		inRules = true
+
+		group := robotsGroup(agents, agent, &specific, &generic)
+		if group == nil {
+			continue
+		}
+		switch key {
+		case "allow", "disallow":
+			// un Disallow vacío permite todo, es lo mismo que no tener la regla
+			if value == "" {
+				continue
+			}
+			group.rules = append(group.rules, robotsRule{pattern: value, match: robotsPattern(value), allow: key == "allow"})
+		case "crawl-delay":
+			if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+				group.CrawlDelay = time.Duration(seconds * float64(time.Second))
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case specific != nil:
+		return specific, nil
+	case generic != nil:
+		return generic, nil
+	}
+	return &RobotsRules{}, nil
+}
+
+// robotsGroup devuelve a qué reglas se agregan las del grupo de agents: las propias si el grupo nombra a
+// nuestro agente, las de "*" si lo nombra o nil si el grupo es de otros agentes
+func robotsGroup(agents []string, agent string, specific, generic **RobotsRules) *RobotsRules {
+	for _, a := range agents {
+		if a != "*" && a == agent {
+			if *specific == nil {
+				*specific = &RobotsRules{}
+			}
+			return *specific
+		}
+	}
+	for _, a := range agents {
+		if a == "*" {
+			if *generic == nil {
+				*generic = &RobotsRules{}
+			}
+			return *generic
+		}
+	}
+	return nil
+}
+
+// productToken devuelve el nombre de producto de un User-Agent en minúsculas: "Tacticas-Arq-Go/1.0 (+url)"
+// es "tacticas-arq-go"
+func productToken(agent string) string {
+	token, _, _ := strings.Cut(strings.TrimSpace(agent), " ")
+	token, _, _ = strings.Cut(token, "/")
+	return strings.ToLower(token)
+}
+
+// robotsPattern convierte un camino de robots.txt en una expresión regular: * es cualquier texto
+// y un $ al final indica que el camino tiene que terminar ahí
+func robotsPattern(pattern string) *regexp.Regexp {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+	expr := "^" + strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, ".*")
+	if anchored {
+		expr += "$"
+	}
+	return regexp.MustCompile(expr)
+}
+
+// Allowed indica si las reglas permiten pedir el camino (con la query, por ejemplo /buscar?q=x)
+func (r *RobotsRules) Allowed(path string) bool {
+	if r.unreachable {
+		return false
+	}
+	best := -1
+	allowed := true
+	for _, rule := range r.rules {
+		if !rule.match.MatchString(path) {
+			continue
+		}
+		if n := len(rule.pattern); n > best || (n == best && rule.allow) {
+			best, allowed = n, rule.allow
+		}
+	}
+	return allowed
+}
+
+// Robots descarga y guarda el robots.txt de cada sitio y aplica sus reglas antes de cada pedido
+type Robots struct {
+	Client    *http.Client
+	UserAgent string
+	now       func() time.Time
+
+	mu    sync.Mutex
+	sites map[string]*robotsSite
+}
+
+// robotsSite son las reglas de un sitio, mu se toma mientras se descarga el robots.txt para que se pida
+// una sola vez aunque lleguen varios urls del sitio a la vez
+type robotsSite struct {
+	mu    sync.Mutex
+	rules *RobotsRules
+	// expires es cuándo se vuelve a pedir el robots.txt, cero para nunca
+	expires time.Time
+	delay   *tokenBucket
+}
+
+func NewRobots(client *http.Client) *Robots {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Robots{Client: client, UserAgent: UserAgent, now: time.Now, sites: map[string]*robotsSite{}}
+}
+
+// Allow devuelve ErrRobotsDisallowed si el robots.txt del sitio no permite pedir el url, y si lo permite
+// espera lo que indique su Crawl-delay. los urls que no son http o https siempre se permiten, y todos con r nil
+func (r *Robots) Allow(ctx context.Context, rawURL string) error {
+	if r == nil {
+		return nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil
+	}
+
+	site := r.site(u.Scheme + "://" + u.Host)
+	site.mu.Lock()
+	if site.rules == nil || !site.expires.IsZero() && !r.now().Before(site.expires) {
+		site.rules = r.fetch(u.Scheme + "://" + u.Host + "/robots.txt")
+		site.expires = time.Time{}
+		if site.rules.unreachable {
+			site.expires = r.now().Add(RobotsRetry)
+		}
+		if site.rules.CrawlDelay > 0 && site.delay == nil {
+			site.delay = newTokenBucket(float64(time.Second)/float64(site.rules.CrawlDelay), 1)
+		}
+	}
+	rules, delay := site.rules, site.delay
+	site.mu.Unlock()
+
+	if rules.unreachable {
+		return ErrRobotsUnreachable
+	}
+
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	if !rules.Allowed(path) {
+		return ErrRobotsDisallowed
+	}
+	return delay.wait(ctx)
+}
+
+func (r *Robots) site(origin string) *robotsSite {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s, ok := r.sites[origin]
+	if !ok {
+		s = &robotsSite{}
+		r.sites[origin] = s
+	}
+	return s
+}
+
+// fetch descarga robots.txt: si no existe (4xx) devuelve reglas que permiten todo y si responde 5xx o no se
+// puede descargar reglas que no permiten nada (RFC 9309 2.3.1). no usa el context del pedido porque las
+// reglas se comparten con todos los urls del sitio
+func (r *Robots) fetch(robotsURL string) *RobotsRules {
+	unreachable := &RobotsRules{unreachable: true}
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultProbeTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, robotsURL, nil)
+	if err != nil {
+		return unreachable
+	}
+	req.Header.Set("User-Agent", r.UserAgent)
+	response, err := r.Client.Do(req)
+	if err != nil {
+		return unreachable
+	}
+	defer DrainAndClose(response.Body)
+	switch {
+	case response.StatusCode >= 500:
+		return unreachable
+	case response.StatusCode != http.StatusOK:
+		// 4xx, o más redirecciones de las que sigue el cliente: el sitio no tiene robots.txt
+		return &RobotsRules{}
+	}
+
+	rules, err := ParseRobots(response.Body, r.UserAgent)
+	if err != nil {
+		return unreachable
+	}
+	return rules
+}
+
+// RespectRobots es el Middleware que no verifica los urls que el robots.txt del sitio no permite
+// y espera el Crawl-delay del sitio entre verificaciones
+func RespectRobots(r *Robots) Middleware {
+	return func(next Prober) Prober {
+		return ProberFunc(func(ctx context.Context, rawURL string) Result {
+			start := time.Now()
+			if err := r.Allow(ctx, rawURL); err != nil {
+				return Result{URL: rawURL, Attempt: 1, Err: err, Throttled: time.Since(start)}
+			}
+			throttled := time.Since(start)
+			res := next.Probe(ctx, rawURL)
+			res.Throttled += throttled
+			return res
+		})
+	}
+}
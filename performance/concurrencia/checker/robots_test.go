@@ -0,0 +1,181 @@
+package checker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const robotsTxt = `
+# comentario
+User-agent: otro-bot
+Disallow: /
+
+User-agent: *
+Disallow: /privado
+Crawl-delay: 5
+
+User-agent: Tacticas-Arq-Go
+User-agent: google
+Disallow: /admin
+Allow: /admin/public
+Disallow: /*.pdf$
+Disallow: /buscar?
+Crawl-delay: 0.05
+`
+
+func TestParseRobotsUsesTheMostSpecificGroup(t *testing.T) {
+	rules, err := ParseRobots(strings.NewReader(robotsTxt), UserAgent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rules.CrawlDelay != 50*time.Millisecond {
+		t.Errorf("CrawlDelay = %v, se esperaba 50ms", rules.CrawlDelay)
+	}
+
+	tests := map[string]bool{
+		"/":                    true,
+		"/privado":             true, // es una regla del grupo *, que no aplica al haber un grupo propio
+		"/admin":               false,
+		"/admin/users":         false,
+		"/admin/public/x":      true, // la regla más larga gana
+		"/docs/manual.pdf":     false,
+		"/docs/manual.pdf?v=2": true, // $ indica que el camino termina ahí
+		"/buscar?q=go":         false,
+		"/buscar":              true,
+	}
+	for path, want := range tests {
+		if got := rules.Allowed(path); got != want {
+			t.Errorf("Allowed(%q) = %v, se esperaba %v", path, got, want)
+		}
+	}
+}
+
+func TestParseRobotsFallsBackToWildcardGroup(t *testing.T) {
+	rules, err := ParseRobots(strings.NewReader(robotsTxt), "curl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rules.Allowed("/privado/x") || !rules.Allowed("/admin") {
+		t.Error("sin un grupo propio se debían usar las reglas de *")
+	}
+	if rules.CrawlDelay != 5*time.Second {
+		t.Errorf("CrawlDelay = %v, se esperaba 5s", rules.CrawlDelay)
+	}
+}
+
+func TestRespectRobotsSkipsDisallowedAndFetchesOnce(t *testing.T) {
+	var fetches int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			atomic.AddInt32(&fetches, 1)
+			fmt.Fprint(w, "User-agent: *\nDisallow: /privado\n")
+		}
+	}))
+	defer srv.Close()
+
+	prober := RespectRobots(NewRobots(srv.Client()))(ProberFunc(func(ctx context.Context, url string) Result {
+		return Result{URL: url, StatusCode: http.StatusOK}
+	}))
+
+	if res := prober.Probe(context.Background(), srv.URL+"/privado/x"); !errors.Is(res.Err, ErrRobotsDisallowed) || res.Kind() != NotChecked {
+		t.Errorf("/privado/x no se debía verificar: %+v", res)
+	}
+	if res := prober.Probe(context.Background(), srv.URL+"/publico"); !res.OK() {
+		t.Errorf("/publico se debía verificar: %+v", res)
+	}
+	if n := atomic.LoadInt32(&fetches); n != 1 {
+		t.Errorf("robots.txt se descargó %d veces, se esperaba una", n)
+	}
+}
+
+func TestRobotsAllowsEverythingWithoutRobotsTxt(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	robots := NewRobots(srv.Client())
+	if err := robots.Allow(context.Background(), srv.URL+"/lo/que/sea"); err != nil {
+		t.Errorf("sin robots.txt se debía permitir todo: %v", err)
+	}
+	var none *Robots
+	if err := none.Allow(context.Background(), srv.URL); err != nil {
+		t.Errorf("un Robots nil debía permitir todo: %v", err)
+	}
+}
+
+func TestRobotsDisallowsEverythingWhileRobotsTxtIsUnreachable(t *testing.T) {
+	var fetches, failing atomic.Int32
+	failing.Store(1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		if failing.Load() == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, "User-agent: *\nDisallow: /privado\n")
+	}))
+	defer srv.Close()
+
+	robots := NewRobots(srv.Client())
+	now := time.Now()
+	robots.now = func() time.Time { return now }
+	for i := 0; i < 2; i++ {
+		if err := robots.Allow(context.Background(), srv.URL+"/publico"); !errors.Is(err, ErrRobotsUnreachable) {
+			t.Errorf("con 503 en robots.txt no se debía permitir nada: %v", err)
+		}
+	}
+	if fetches.Load() != 1 {
+		t.Errorf("robots.txt se pidió %d veces antes de RobotsRetry, se esperaba una", fetches.Load())
+	}
+
+	failing.Store(0)
+	now = now.Add(RobotsRetry)
+	if err := robots.Allow(context.Background(), srv.URL+"/publico"); err != nil {
+		t.Errorf("pasado RobotsRetry se debía volver a pedir robots.txt: %v", err)
+	}
+	if err := robots.Allow(context.Background(), srv.URL+"/privado"); !errors.Is(err, ErrRobotsDisallowed) {
+		t.Errorf("/privado no se debía permitir: %v", err)
+	}
+
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	if err := robots.Allow(context.Background(), closed.URL+"/"); !errors.Is(err, ErrRobotsUnreachable) {
+		t.Errorf("sin poder conectarse no se debía permitir nada: %v", err)
+	}
+}
+
+func TestParseRobotsMatchesTheProductToken(t *testing.T) {
+	const txt = "User-agent: go\nDisallow: /\n\nUser-agent: TACTICAS-ARQ-GO\nDisallow: /admin\n"
+	rules, err := ParseRobots(strings.NewReader(txt), "Tacticas-Arq-Go/2.0 (+https://example.com)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// el grupo "go" es de otro agente aunque su nombre esté contenido en el nuestro
+	if !rules.Allowed("/") || rules.Allowed("/admin") {
+		t.Error("se esperaban las reglas del grupo tacticas-arq-go")
+	}
+}
+
+func TestRobotsWaitsCrawlDelay(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "User-agent: *\nCrawl-delay: 0.05\n")
+	}))
+	defer srv.Close()
+
+	robots := NewRobots(srv.Client())
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := robots.Allow(context.Background(), srv.URL+"/"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("3 pedidos con Crawl-delay de 50ms tardaron %v, se esperaban al menos 100ms", elapsed)
+	}
+}
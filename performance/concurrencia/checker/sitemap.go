@@ -0,0 +1,142 @@
+package checker
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+/*
+	carga de urls desde sitemap.xml.
+
+	en lugar de escribir a mano la lista de urls de un sitio se pueden tomar del sitemap que el sitio publica
+	(https://www.sitemaps.org/protocol.html). un sitemap es un <urlset> con los urls o un <sitemapindex> que
+	lista otros sitemaps, y cualquiera de los dos puede venir comprimido con gzip (sitemap.xml.gz). los índices
+	se siguen hasta maxSitemapDepth niveles y cada sitemap se lee una sola vez, así un índice que se lista a
+	sí mismo no queda en un ciclo.
+*/
+
+// límites del protocolo: un sitemap tiene como máximo 50000 urls y 50 MB sin comprimir
+const (
+	MaxSitemapURLs  = 50000
+	maxSitemapBytes = 50 << 20
+	maxSitemapDepth = 3
+)
+
+// sitemapDoc sirve tanto para un <urlset> como para un <sitemapindex>, cada uno llena su lista
+type sitemapDoc struct {
+	XMLName  xml.Name
+	URLs     []sitemapLoc `xml:"url"`
+	Sitemaps []sitemapLoc `xml:"sitemap"`
+}
+
+type sitemapLoc struct {
+	Loc string `xml:"loc"`
+}
+
+// LoadSitemap descarga el sitemap (o índice de sitemaps) y devuelve hasta max urls, 0 para MaxSitemapURLs.
+// cada sitemap se pide con el User-Agent, los headers y la autenticación que requests indica para su url
+func LoadSitemap(ctx context.Context, client *http.Client, requests *RequestConfigs, sitemapURL string, max int) ([]string, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if max <= 0 {
+		max = MaxSitemapURLs
+	}
+	loader := sitemapLoader{client: client, requests: requests, max: max, seen: map[string]bool{}}
+	if err := loader.load(ctx, sitemapURL, 0); err != nil {
+		return nil, err
+	}
+	return loader.urls, nil
+}
+
+type sitemapLoader struct {
+	client   *http.Client
+	requests *RequestConfigs
+	max      int
+	seen     map[string]bool
+	urls     []string
+}
+
+func (l *sitemapLoader) load(ctx context.Context, sitemapURL string, depth int) error {
+	if l.seen[sitemapURL] || len(l.urls) >= l.max {
+		return nil
+	}
+	l.seen[sitemapURL] = true
+
+	doc, err := l.fetch(ctx, sitemapURL)
+	if err != nil {
+		return err
+	}
+
+	switch doc.XMLName.Local {
+	case "urlset":
+		for _, u := range doc.URLs {
+			if len(l.urls) >= l.max {
+				break
+			}
+			if loc := strings.TrimSpace(u.Loc); loc != "" {
+				l.urls = append(l.urls, loc)
+			}
+		}
+	case "sitemapindex":
+		if depth >= maxSitemapDepth {
+			return fmt.Errorf("%s: índices de sitemaps anidados en más de %d niveles", sitemapURL, maxSitemapDepth)
+		}
+		for _, s := range doc.Sitemaps {
+			if err := l.load(ctx, strings.TrimSpace(s.Loc), depth+1); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("%s: no es un sitemap, el elemento raíz es <%s>", sitemapURL, doc.XMLName.Local)
+	}
+	return nil
+}
+
+func (l *sitemapLoader) fetch(ctx context.Context, sitemapURL string) (*sitemapDoc, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, sitemapURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	// la configuración de los pedidos está por url normalizado, igual que la lista a verificar
+	key, err := Normalize(sitemapURL)
+	if err != nil {
+		key = sitemapURL
+	}
+	if err := l.requests.For(key).Apply(req); err != nil {
+		return nil, fmt.Errorf("%s: %w", sitemapURL, err)
+	}
+	response, err := l.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer DrainAndClose(response.Body)
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: status %d", sitemapURL, response.StatusCode)
+	}
+
+	// el gzip se reconoce por sus dos primeros bytes y no por la extensión o el Content-Type, que los
+	// servidores informan de formas distintas (y el cliente ya descomprime el Content-Encoding: gzip)
+	body := bufio.NewReader(response.Body)
+	var r io.Reader = body
+	if magic, _ := body.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", sitemapURL, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	var doc sitemapDoc
+	if err := xml.NewDecoder(io.LimitReader(r, maxSitemapBytes)).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", sitemapURL, err)
+	}
+	return &doc, nil
+}
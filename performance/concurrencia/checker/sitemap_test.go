@@ -0,0 +1,128 @@
+package checker
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadSitemapFollowsIndexAndGzip(t *testing.T) {
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<url><loc>http://example.com/c</loc></url>
+</urlset>`)
+	w.Close()
+
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sitemap.xml":
+			// el índice se lista a sí mismo, no tiene que quedar en un ciclo
+			fmt.Fprintf(w, `<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<sitemap><loc>%[1]s/paginas.xml</loc></sitemap>
+	<sitemap><loc>%[1]s/mas.xml.gz</loc></sitemap>
+	<sitemap><loc>%[1]s/sitemap.xml</loc></sitemap>
+</sitemapindex>`, srv.URL)
+		case "/paginas.xml":
+			fmt.Fprint(w, `<urlset><url><loc> http://example.com/a </loc></url><url><loc>http://example.com/b</loc></url></urlset>`)
+		case "/mas.xml.gz":
+			w.Write(gz.Bytes())
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	urls, err := LoadSitemap(context.Background(), srv.Client(), nil, srv.URL+"/sitemap.xml", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"http://example.com/a", "http://example.com/b", "http://example.com/c"}
+	if !reflect.DeepEqual(urls, want) {
+		t.Errorf("urls = %v, se esperaba %v", urls, want)
+	}
+
+	if urls, _ := LoadSitemap(context.Background(), srv.Client(), nil, srv.URL+"/sitemap.xml", 2); len(urls) != 2 {
+		t.Errorf("con máximo 2 se cargaron %d urls", len(urls))
+	}
+	if _, err := LoadSitemap(context.Background(), srv.Client(), nil, srv.URL+"/no-existe.xml", 0); err == nil {
+		t.Error("un sitemap que no existe debía dar error")
+	}
+}
+
+// los sitemaps se descargan con la red y los headers de los flags del prober, no con http.DefaultClient
+func TestInventoryLoadsSitemapsWithTheProberConfig(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Host, "sitemaps.test:") {
+			t.Errorf("Host = %s", r.Host)
+		}
+		if ua, token := r.UserAgent(), r.Header.Get("X-Token"); ua != "monitor/1.0" || token != "secreto" {
+			t.Errorf("el sitemap se pidió con User-Agent %q y X-Token %q", ua, token)
+		}
+		fmt.Fprint(w, `<urlset><url><loc>http://example.com/a</loc></url></urlset>`)
+	}))
+	defer srv.Close()
+	port := srv.URL[strings.LastIndex(srv.URL, ":")+1:]
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	probe := RegisterProbeFlags(fs)
+	inv := RegisterInventoryFlag(fs, probe)
+	// sitemaps.test solo existe con -resolve
+	err := fs.Parse([]string{"-user-agent=monitor/1.0", "-header=X-Token: secreto", "-resolve=sitemaps.test=127.0.0.1",
+		"-sitemap=http://sitemaps.test:" + port + "/sitemap.xml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := probe.Prober(slog.New(slog.NewTextHandler(io.Discard, nil))); err != nil {
+		t.Fatal(err)
+	}
+
+	urls, err := inv.URLs(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(urls) != 1 || urls[0] != "http://example.com/a" {
+		t.Errorf("urls = %v", urls)
+	}
+}
+
+// -sitemap-max es el máximo entre todos los sitemaps, no el de cada uno
+func TestInventorySitemapMaxIsTotal(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/"), ".xml")
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `<urlset><url><loc>http://example.com/%[1]s/1</loc></url><url><loc>http://example.com/%[1]s/2</loc></url></urlset>`, name)
+	}))
+	defer srv.Close()
+
+	inv := &Inventory{Sitemaps: srv.URL + "/uno.xml," + srv.URL + "/dos.xml", SitemapMax: 3}
+	urls, err := inv.URLs(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"http://example.com/uno/1", "http://example.com/uno/2", "http://example.com/dos/1"}
+	if !reflect.DeepEqual(urls, want) {
+		t.Errorf("urls = %v, se esperaba %v", urls, want)
+	}
+
+	// si el primero ya llega al máximo el segundo no se descarga
+	inv.SitemapMax = 2
+	inv.Sitemaps = srv.URL + "/uno.xml," + srv.URL + "/no-existe"
+	if urls, err := inv.URLs(nil); err != nil || len(urls) != 2 {
+		t.Errorf("urls = %v, %v", urls, err)
+	}
+}
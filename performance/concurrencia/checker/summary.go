@@ -0,0 +1,118 @@
+package checker
+
+import (
+	"log/slog"
+	"sort"
+	"sync"
+)
+
+// Summary acumula los resultados de una ejecución para registrar al final cuántos urls respondieron,
+// cuántos no y por qué categoría de error fallaron los demás, y en una sección aparte los certificados
+// TLS que vencen pronto. lo pueden usar varias gorutinas a la vez
+type Summary struct {
+	mu     sync.Mutex
+	total  int
+	ok     int
+	failed int
+	errors map[ErrorKind]int
+
+	// urls https inspeccionados y los que tienen advertencias sobre sus certificados
+	tlsChecked int
+	tlsWarned  []Result
+
+	// conexiones que se abrieron y que se reutilizaron del pool, ver ConnUsage
+	conns ConnUsage
+}
+
+// Add suma un resultado al resumen
+func (s *Summary) Add(results ...Result) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, r := range results {
+		s.total++
+		s.conns.New += r.Conns.New
+		s.conns.Reused += r.Conns.Reused
+		if r.TLS != nil {
+			s.tlsChecked++
+			if len(r.TLS.Warnings) > 0 {
+				s.tlsWarned = append(s.tlsWarned, r)
+			}
+		}
+		switch {
+		case r.Err != nil:
+			if s.errors == nil {
+				s.errors = map[ErrorKind]int{}
+			}
+			s.errors[r.Kind()]++
+		case r.OK():
+			s.ok++
+		default:
+			s.failed++
+		}
+	}
+}
+
+// Errors devuelve la cantidad de resultados con error de cada categoría
+func (s *Summary) Errors() map[ErrorKind]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	errors := make(map[ErrorKind]int, len(s.errors))
+	for k, n := range s.errors {
+		errors[k] = n
+	}
+	return errors
+}
+
+// Conns devuelve el total de conexiones abiertas y reutilizadas
+func (s *Summary) Conns() ConnUsage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.conns
+}
+
+// Log registra el resumen, los errores van agrupados por categoría en el campo errors
+func (s *Summary) Log(logger *slog.Logger) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kinds := make([]ErrorKind, 0, len(s.errors))
+	for k := range s.errors {
+		kinds = append(kinds, k)
+	}
+	sort.Slice(kinds, func(i, j int) bool { return kinds[i] < kinds[j] })
+
+	errors := make([]any, 0, 2*len(kinds))
+	for _, k := range kinds {
+		errors = append(errors, k.String(), s.errors[k])
+	}
+	logger.Info("resumen", "total", s.total, "ok", s.ok, "false", s.failed, slog.Group("errors", errors...))
+
+	// sección de conexiones, la proporción de reutilizadas muestra cuánto sirve el pool (keep-alive)
+	if total := s.conns.New + s.conns.Reused; total > 0 {
+		logger.Info("resumen conexiones", "new", s.conns.New, "reused", s.conns.Reused,
+			"reuse_ratio", float64(s.conns.Reused)/float64(total))
+	}
+
+	if s.tlsChecked == 0 {
+		return
+	}
+	// sección de certificados: primero los que vencen antes
+	sort.Slice(s.tlsWarned, func(i, j int) bool {
+		return s.tlsWarned[i].TLS.NotAfter().Before(s.tlsWarned[j].TLS.NotAfter())
+	})
+	logger.Info("resumen tls", "checked", s.tlsChecked, "warnings", len(s.tlsWarned))
+	for _, r := range s.tlsWarned {
+		for _, warning := range r.TLS.Warnings {
+			logger.Warn(warning, KeyURL, RedactURL(r.URL), KeyCertNotAfter, r.TLS.NotAfter())
+		}
+	}
+}
+
+// CertWarnings devuelve los resultados con advertencias sobre sus certificados
+func (s *Summary) CertWarnings() []Result {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Result(nil), s.tlsWarned...)
+}
@@ -0,0 +1,106 @@
+package checker
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+/*
+	inspección de los certificados TLS.
+
+	la mayoría de los sitios terminan en https luego de las redirecciones, y un certificado vencido es una caída
+	que uno mismo se provoca. de cada respuesta https se guarda la cadena de certificados que presentó el
+	servidor (subject, issuer, SANs y vencimiento), se verifica que el certificado sea del host y se genera una
+	advertencia si algún certificado de la cadena vence dentro de la ventana configurada (-cert-warn-window).
+
+	si la verificación del cliente http falla (por ejemplo con un certificado vencido) la cadena se saca del
+	error, así el resultado dice por qué falló además de ser un TLSError.
+*/
+
+// CertInfo son los datos de un certificado de la cadena
+type CertInfo struct {
+	Subject  string    `json:"subject"`
+	Issuer   string    `json:"issuer"`
+	DNSNames []string  `json:"dns_names,omitempty"`
+	NotAfter time.Time `json:"not_after"`
+}
+
+// TLSInfo es lo que se inspeccionó del TLS de la respuesta, el primer certificado de Chain es el del servidor
+type TLSInfo struct {
+	Chain []CertInfo `json:"chain"`
+	// HostnameError es el error de verificar que el certificado sea del host, vacío si lo es
+	HostnameError string `json:"hostname_error,omitempty"`
+	// advertencias sobre los certificados: vencidos o que vencen dentro de la ventana
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// NotAfter devuelve el vencimiento más próximo de la cadena
+func (t *TLSInfo) NotAfter() time.Time {
+	var first time.Time
+	for _, c := range t.Chain {
+		if first.IsZero() || c.NotAfter.Before(first) {
+			first = c.NotAfter
+		}
+	}
+	return first
+}
+
+// inspectTLS arma el TLSInfo de la cadena que presentó host, con advertencias para los certificados
+// vencidos o que vencen antes de now + window
+func inspectTLS(certs []*x509.Certificate, host string, window time.Duration, now time.Time) *TLSInfo {
+	if len(certs) == 0 {
+		return nil
+	}
+
+	info := &TLSInfo{}
+	for _, c := range certs {
+		info.Chain = append(info.Chain, CertInfo{
+			Subject:  c.Subject.String(),
+			Issuer:   c.Issuer.String(),
+			DNSNames: c.DNSNames,
+			NotAfter: c.NotAfter,
+		})
+
+		switch left := c.NotAfter.Sub(now); {
+		case left <= 0:
+			info.Warnings = append(info.Warnings, fmt.Sprintf("el certificado %q venció el %s", c.Subject, c.NotAfter.Format(time.DateOnly)))
+		case left <= window:
+			info.Warnings = append(info.Warnings, fmt.Sprintf("el certificado %q vence el %s (en %s)", c.Subject, c.NotAfter.Format(time.DateOnly), left.Round(time.Hour)))
+		}
+	}
+	if err := certs[0].VerifyHostname(host); err != nil {
+		info.HostnameError = err.Error()
+		info.Warnings = append(info.Warnings, fmt.Sprintf("el certificado no es de %s", host))
+	}
+	return info
+}
+
+// tlsFromError saca del error del cliente http la cadena que presentó el servidor cuando falló su
+// verificación, junto con el host al que se estaba conectando
+func tlsFromError(err error) (certs []*x509.Certificate, host string) {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		if u, parseErr := url.Parse(urlErr.URL); parseErr == nil {
+			host = u.Hostname()
+		}
+	}
+
+	var (
+		verifyErr   *tls.CertificateVerificationError
+		hostnameErr x509.HostnameError
+		invalidErr  x509.CertificateInvalidError
+	)
+	switch {
+	case errors.As(err, &verifyErr):
+		certs = verifyErr.UnverifiedCertificates
+	case errors.As(err, &hostnameErr):
+		certs = []*x509.Certificate{hostnameErr.Certificate}
+	case errors.As(err, &invalidErr):
+		certs = []*x509.Certificate{invalidErr.Cert}
+	}
+	return certs, host
+}
@@ -0,0 +1,95 @@
+package checker
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHeadProberInspectsCertificates(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	prober := NewHeadProber(server.Client())
+
+	res := prober.Probe(context.Background(), server.URL)
+	if !res.OK() || res.TLS == nil || len(res.TLS.Chain) == 0 {
+		t.Fatalf("se esperaba la cadena de certificados: %+v", res)
+	}
+	if res.TLS.HostnameError != "" || len(res.TLS.Warnings) != 0 {
+		t.Errorf("el certificado de httptest es de 127.0.0.1 y no vence pronto: %+v", res.TLS)
+	}
+
+	// con una ventana más larga que lo que le queda al certificado se genera la advertencia
+	prober.CertWarnWindow = time.Until(res.TLS.NotAfter()) + time.Hour
+	res = prober.Probe(context.Background(), server.URL)
+	if len(res.TLS.Warnings) != 1 || !strings.Contains(res.TLS.Warnings[0], "vence el") {
+		t.Errorf("se esperaba una advertencia de vencimiento: %+v", res.TLS.Warnings)
+	}
+
+	var s Summary
+	s.Add(res)
+	if got := s.CertWarnings(); len(got) != 1 || got[0].URL != server.URL {
+		t.Errorf("el resumen debía tener la advertencia del certificado: %+v", got)
+	}
+}
+
+func TestHeadProberReportsExpiredCertificate(t *testing.T) {
+	cert, pool := expiredCertificate(t)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{cert}}
+	server.StartTLS()
+	defer server.Close()
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}}
+	res := NewHeadProber(client).Probe(context.Background(), server.URL)
+
+	if res.Kind() != TLSError {
+		t.Fatalf("categoría = %s, se esperaba tls (error: %v)", res.Kind(), res.Err)
+	}
+	if res.TLS == nil || len(res.TLS.Warnings) == 0 || !strings.Contains(res.TLS.Warnings[0], "venció") {
+		t.Errorf("se esperaba la cadena sacada del error con la advertencia de vencido: %+v", res.TLS)
+	}
+}
+
+// expiredCertificate genera un certificado autofirmado para 127.0.0.1 que venció ayer
+func expiredCertificate(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "vencido"},
+		NotBefore:             time.Now().Add(-48 * time.Hour),
+		NotAfter:              time.Now().Add(-24 * time.Hour),
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(parsed)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: parsed}, pool
+}
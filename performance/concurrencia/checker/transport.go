@@ -0,0 +1,128 @@
+package checker
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync/atomic"
+	"time"
+)
+
+/*
+	transport http propio con pool de conexiones y métricas de reutilización.
+
+	abrir una conexión cuesta el DNS, el handshake TCP y el de TLS, que con https suele ser más que el propio
+	HEAD. reutilizar las conexiones (keep-alive) es una táctica de performance: el costo de abrir la conexión se
+	paga una vez por host y no una vez por pedido. para eso hacen falta dos cosas:
+		- un transport compartido por todas las verificaciones con lugar en el pool para varias conexiones por
+		  host (el http.DefaultTransport guarda solo 2 conexiones libres por host, MaxIdleConnsPerHost)
+		- leer el body hasta el final y cerrarlo: una conexión vuelve al pool solo cuando se terminó de leer la
+		  respuesta, si no se cierra (y se pierde) o queda ocupada para siempre. ver DrainAndClose
+	con httptrace cada resultado registra cuántas conexiones nuevas abrió y cuántas reutilizó (Result.Conns), y el
+	resumen muestra los totales. con -disable-keep-alives se puede comparar contra abrir una conexión por pedido,
+	ver BenchmarkConnectionReuse.
+*/
+
+// TransportConfig es la configuración del transport http, un valor 0 usa el defecto de http.Transport
+type TransportConfig struct {
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	IdleConnTimeout     time.Duration
+	DisableKeepAlives   bool
+	// HTTP2 negocia HTTP/2 con los servidores https que lo soportan, con false siempre HTTP/1.1
+	HTTP2               bool
+	DialTimeout         time.Duration
+	TLSHandshakeTimeout time.Duration
+}
+
+// DefaultTransportConfig es la configuración de los flags por defecto: más conexiones libres por host que
+// http.DefaultTransport, ya que las estrategias concurrentes hacen varios pedidos en paralelo al mismo host
+func DefaultTransportConfig() TransportConfig {
+	return TransportConfig{
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 10,
+		IdleConnTimeout:     90 * time.Second,
+		HTTP2:               true,
+		DialTimeout:         DefaultProbeTimeout,
+		TLSHandshakeTimeout: 10 * time.Second,
+	}
+}
+
+// NewClient arma el cliente http que comparten todas las verificaciones con el transport configurado y la red
+// de network (proxy, resolver, hosts fijos y dirección local)
+func NewClient(cfg TransportConfig, network NetworkConfig) (*http.Client, error) {
+	proxy, err := network.proxy()
+	if err != nil {
+		return nil, err
+	}
+	dial, err := network.DialContext()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.DialTimeout > 0 {
+		dial = dialTimeout(dial, cfg.DialTimeout)
+	}
+
+	transport := &http.Transport{
+		Proxy:               proxy,
+		DialContext:         dial,
+		MaxIdleConns:        cfg.MaxIdleConns,
+		MaxIdleConnsPerHost: cfg.MaxIdleConnsPerHost,
+		IdleConnTimeout:     cfg.IdleConnTimeout,
+		DisableKeepAlives:   cfg.DisableKeepAlives,
+		TLSHandshakeTimeout: cfg.TLSHandshakeTimeout,
+		ForceAttemptHTTP2:   cfg.HTTP2,
+	}
+	if !cfg.HTTP2 {
+		// un mapa vacío (no nil) es la forma de desactivar HTTP/2 en un http.Transport
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+	return &http.Client{Transport: transport}, nil
+}
+
+func dialTimeout(dial DialFunc, timeout time.Duration) DialFunc {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return dial(ctx, network, address)
+	}
+}
+
+// máximo que se lee de un body para devolver la conexión al pool, con bodies más grandes conviene perder la
+// conexión antes que descargar todo
+const maxDrainBytes = 256 << 10
+
+// DrainAndClose lee lo que queda del body (hasta maxDrainBytes) y lo cierra, así la conexión vuelve al pool
+func DrainAndClose(body io.ReadCloser) {
+	io.CopyN(io.Discard, body, maxDrainBytes)
+	body.Close()
+}
+
+// ConnUsage es cuántas conexiones abrió una verificación y cuántas tomó del pool. puede ser más de una
+// si hubo redirecciones o se reintentó con GET
+type ConnUsage struct {
+	New    int
+	Reused int
+}
+
+// traceConns devuelve el context que registra cada conexión que obtiene el pedido y la función con la que se
+// leen los contadores cuando terminó
+func traceConns(ctx context.Context) (context.Context, func() ConnUsage) {
+	// los callbacks de httptrace pueden llegar desde otras gorutinas del transport
+	var newConns, reused atomic.Int32
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			if info.Reused {
+				reused.Add(1)
+			} else {
+				newConns.Add(1)
+			}
+		},
+	}
+	return httptrace.WithClientTrace(ctx, trace), func() ConnUsage {
+		return ConnUsage{New: int(newConns.Load()), Reused: int(reused.Load())}
+	}
+}
@@ -0,0 +1,154 @@
+package checker
+
+import (
+	"context"
+	"crypto/tls"
+	"flag"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// clientFor arma un cliente con NewClient que confía en el certificado del servidor de prueba
+func clientFor(t testing.TB, srv *httptest.Server, cfg TransportConfig) *http.Client {
+	t.Helper()
+	client, err := NewClient(cfg, NetworkConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if srv.TLS != nil {
+		// solo las autoridades, el resto de la configuración TLS (como los protocolos ALPN) la arma NewClient
+		roots := srv.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs
+		client.Transport.(*http.Transport).TLSClientConfig = &tls.Config{RootCAs: roots}
+	}
+	return client
+}
+
+func TestHeadProberReusesConnections(t *testing.T) {
+	// un body más grande que el máximo que se lee, la conexión igual tiene que volver al pool
+	body := strings.Repeat("x", 100<<10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	prober := NewHeadProber(clientFor(t, srv, DefaultTransportConfig()))
+	prober.Expectations = &Expectations{Default: Expectation{Method: http.MethodGet, MaxBodyBytes: 1 << 10}}
+
+	var summary Summary
+	for i := 0; i < 3; i++ {
+		res := prober.Probe(context.Background(), srv.URL)
+		if !res.OK() {
+			t.Fatalf("%+v", res)
+		}
+		summary.Add(res)
+	}
+	if got := summary.Conns(); got != (ConnUsage{New: 1, Reused: 2}) {
+		t.Errorf("conexiones = %+v, se esperaba una nueva y dos reutilizadas", got)
+	}
+}
+
+func TestDisableKeepAlivesOpensAConnectionPerRequest(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	cfg := DefaultTransportConfig()
+	cfg.DisableKeepAlives = true
+	prober := NewHeadProber(clientFor(t, srv, cfg))
+
+	var summary Summary
+	for i := 0; i < 3; i++ {
+		summary.Add(prober.Probe(context.Background(), srv.URL))
+	}
+	if got := summary.Conns(); got != (ConnUsage{New: 3}) {
+		t.Errorf("conexiones = %+v, se esperaban tres nuevas", got)
+	}
+}
+
+func TestHTTP2Toggle(t *testing.T) {
+	var proto int
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proto = r.ProtoMajor
+	}))
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+
+	for _, http2 := range []bool{true, false} {
+		cfg := DefaultTransportConfig()
+		cfg.HTTP2 = http2
+		res := NewHeadProber(clientFor(t, srv, cfg)).Probe(context.Background(), srv.URL)
+		if !res.OK() {
+			t.Fatalf("%+v", res)
+		}
+		if want := map[bool]int{true: 2, false: 1}[http2]; proto != want {
+			t.Errorf("con HTTP2 %v el pedido fue HTTP/%d", http2, proto)
+		}
+	}
+}
+
+// BenchmarkConnectionReuse compara verificar un sitio https reutilizando las conexiones del pool contra
+// abrir una conexión (con su handshake TLS) para cada pedido
+func BenchmarkConnectionReuse(b *testing.B) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	for _, keepAlive := range []bool{true, false} {
+		name := "keep-alive"
+		if !keepAlive {
+			name = "sin-keep-alive"
+		}
+		b.Run(name, func(b *testing.B) {
+			cfg := DefaultTransportConfig()
+			cfg.DisableKeepAlives = !keepAlive
+			prober := NewHeadProber(clientFor(b, srv, cfg))
+
+			var summary Summary
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				summary.Add(prober.Probe(context.Background(), srv.URL))
+			}
+			b.StopTimer()
+			b.ReportMetric(float64(summary.Conns().New)/float64(b.N), "conns/op")
+		})
+	}
+}
+
+// un servidor que acepta la conexión y nunca responde no deja a la verificación esperando para siempre
+func TestProberTimesOutWhenServerNeverResponds(t *testing.T) {
+	stop := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-stop:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(stop)
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	cfg := RegisterProbeFlags(fs)
+	if err := fs.Parse([]string{"-timeout=100ms"}); err != nil {
+		t.Fatal(err)
+	}
+	prober, err := cfg.Prober(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan Result, 1)
+	go func() { done <- prober.Probe(context.Background(), srv.URL) }()
+	select {
+	case res := <-done:
+		if res.Kind() != Timeout {
+			t.Errorf("se esperaba un timeout: %+v", res)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("la verificación no terminó con -timeout=100ms")
+	}
+}
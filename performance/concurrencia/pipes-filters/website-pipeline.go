@@ -21,6 +21,9 @@
 	al ejecutar prestar atención al orden en que se despliega el workerId o el url y
 	a la cantidad de segundos que duró la ejecución (con go test -bench)
 
+	los resultados se registran al final del pipeline con logs estructurados (log/slog), con los flags
+	-log-format=json y -log-level=debug se cambia el formato y el nivel
+
 	TO DO - agregar manejo de errores
 
 */
@@ -28,45 +31,81 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
-	"net/http"
+	"log/slog"
+	"os"
+	"os/signal"
 	"sync"
+	"time"
+
+	"arqsoft/tacticas-arq-go/performance/concurrencia/checker"
 )
 
+// logger de la ejecución, main lo configura con los flags -log-format y -log-level
+var logger = slog.Default()
 
-// función extraida de checkWebsite para hacerla mas legible a checkWebsite
-func callHead(url string) string {
-	var ret string
+// prober de cada url, main le agrega las tácticas de los flags (ver checker)
+var prober checker.Prober = checker.NewHeadProber(nil)
 
+// cantidad de gorutinas checkWebsite que leen del canal de urls, main la configura con -workers
+var workers = 2
+
+// result es lo que viaja por los canales desde checkWebsite hasta donde se imprime
+type result struct {
+	checker.Result
+	workerId int
+	message  string
+}
+
+// función extraida de checkWebsite para hacerla mas legible a checkWebsite
+func callHead(ctx context.Context, url string, workerId int) result {
 	// el que sigue es el mismo código que en el ejemplo secuencial
-	response, err := http.Head(url)
-	if err != nil {
-		 ret = fmt.Sprintf("El url %s no existe \n", url)
+	ret := result{Result: prober.Probe(ctx, url), workerId: workerId}
+	if ret.Skipped() {
+		// no se verificó, por ejemplo porque el circuito del host está abierto
+		ret.message = ret.Err.Error()
+	} else if ret.Err != nil {
+		// el mensaje dice por qué falló: no existe, conexión rechazada, timeout, etc.
+		ret.message = "El url falló: " + ret.Kind().Message()
 	} else {
-		// ok Head sin error, si no es ok retorna url:false si es ok url:true
-		if response.StatusCode != http.StatusOK {
-			ret = fmt.Sprintf("El url %s NO responde OK \n", url)
+		// ok Head sin error, si no cumple los criterios de éxito retorna url:false, si los cumple url:true
+		if !ret.OK() {
+			ret.message = "El url NO responde OK"
 		} else {
-			ret = fmt.Sprintf("El url %s responde OK \n", url)
+			ret.message = "El url responde OK"
 		}
 	}
 	return ret
 }
 
+// registra en el log un resultado que salió del pipeline
+func logResult(res result) {
+	urlLogger := logger.With(checker.KeyStage, "checkWebsite", checker.KeyWorkerID, res.workerId)
+	if res.Err != nil {
+		urlLogger.Warn(res.message, res.Attrs()...)
+	} else {
+		urlLogger.Info(res.message, res.Attrs()...)
+	}
+}
+
 
 // esta función es la que llama a las goroutinas que checkean los urls en paralelo 
 // usa el select que en caso de que haya algo en el canal de in lo procesa (callhead) y lo
 // empuja al canal out
 // el select chequea el done y en caso que venga algo en ese canal termina prolijamente la ejecución de 
 // la gorutina
-func checkWebsite(done <- chan struct {}, in <-chan string) <-chan string {
+// workerId identifica a la gorutina en los logs
+// ctx corta los HEAD en curso, el done es para terminar las gorutinas
+func checkWebsite(ctx context.Context, done <- chan struct {}, in <-chan string, workerId int) <-chan result {
 
-	out := make(chan string)
+	out := make(chan result)
 	go func() {
 		defer close(out)
 		for url := range in {
 			select {
-			case out <- callHead(url):
+			case out <- callHead(ctx, url, workerId):
 			case <- done:
 				return	
 			}
@@ -81,14 +120,14 @@ func checkWebsite(done <- chan struct {}, in <-chan string) <-chan string {
 // checkWebsite
 // ver pipeline ( WebsiteStatusChecker()) para ver el caso de uso
 
-func merge(done <-chan struct{}, cs... <-chan string) <-chan string {
+func merge(done <-chan struct{}, cs... <-chan result) <-chan result {
     var wg sync.WaitGroup
-    out := make(chan string)
+    out := make(chan result)
 
 	// esta gorutina saca de los canales de entrada y pasa lo recibido a un canal único (out)
 	// notar que en 2do argumento de merge es un colección de canales
 	// se usa el waitgroup para saber cuantos canales están aportando al merge
-    output := func(c <-chan string) {
+    output := func(c <-chan result) {
 		defer wg.Done()
         for n := range c {
             select {
@@ -118,21 +157,23 @@ func merge(done <-chan struct{}, cs... <-chan string) <-chan string {
 }
 
 
-func feedWebsites(done <- chan struct{}) <-chan string {
+// urls que verifica el main
+var websites = []string{
+	"http://ort.edu.uy",
+	"http://google.com",
+	"http://github.com",
+	"http://arqsoft.com",
+	"http://netflix.com",
+	"http://instagram.com",
+	"http://ingsoft.gaston.com",
+}
+
+func feedWebsites(done <- chan struct{}, urls []string) <-chan string {
 	out := make(chan string)
 	
-	var websites = []string{
-		"http://ort.edu.uy",
-		"http://google.com",
-		"http://github.com",
-		"http://arqsoft.com",
-		"http://netflix.com",
-		"http://instagram.com",
-		"http://ingsoft.gaston.com",
-	}
 	go func ()  {
 		defer close(out)
-		for _, ws := range websites {
+		for _, ws := range urls {
 			select {
 			case out <- ws:
 			case <-done:
@@ -153,28 +194,42 @@ func feedWebsites(done <- chan struct{}) <-chan string {
 	se mergea en un único canal para poder imprimir. 
 	recodar que los pipelines en general tienen un source (el generador), varios filtros y que en general terminan en un sink que 
 	es donde se juntan todas las ramas o donde termina el pipeline
+	si se cancela ctx los HEAD que faltan terminan enseguida como cancelados y el pipeline se vacía solo
 
 */
-func WebsiteStatusChecker()	{
+func WebsiteStatusChecker(ctx context.Context, urls []string)	{
+
+	// se normaliza la lista, ver checker.PrepareURLs
+	prepared := checker.PrepareURLs(urls)
+	prepared.Log(logger)
+	urls = prepared.URLs
+
+	// al final se registra el resumen, ver checker.Summary
+	var summary checker.Summary
+	summary.Add(prepared.Invalid...)
+	defer summary.Log(logger)
 
 	//se crea un canal para comunicar que las gorutinas  terminen su trabajo y salgan prolijamente
 	done := make(chan struct{})
 	defer close(done)
 
 	// empuja por el canal in los urls
-	in := feedWebsites(done)
-
-	// se crear 2 gorutinas que cada una saca de in y escribe a su propio canal de salida
+	in := feedWebsites(done, urls)
 
-	firstFeeder := checkWebsite(done, in)
-	secondFeeder := checkWebsite(done, in)
+	// se crean workers gorutinas (2 por defecto) que cada una saca de in y escribe a su propio canal de salida
+	feeders := make([]<-chan result, 0, workers)
+	for i := 1; i <= workers; i++ {
+		feeders = append(feeders, checkWebsite(ctx, done, in, i))
+	}
+	logger.Debug("pipeline armado", checker.KeyStage, "checkWebsite", "workers", workers)
 
-	// función que mergea lo que pasa en los dos canales de entrada en uno de salida 
-	out := merge(done, firstFeeder, secondFeeder)
+	// función que mergea lo que pasa en los canales de entrada en uno de salida 
+	out := merge(done, feeders...)
 
-	// se imprime el resultado leyendo del canal mergeado.
+	// se registra el resultado leyendo del canal mergeado.
 	for n := range out {
-		fmt.Printf(n)
+		logResult(n)
+		summary.Add(n.Result)
 	}
 	
 }
@@ -184,11 +239,55 @@ func WebsiteStatusChecker()	{
 // solo llama a la función de verificar sitios con un slice de urls, se separó para poder invocar WebsiteStatusChecker(); 
 // desde los tests
 func main() {
-	// declara un array de urls para chequear
+	logConfig := checker.RegisterLogFlags(flag.CommandLine)
+	probeConfig := checker.RegisterProbeFlags(flag.CommandLine)
+	// urls de -inventory y -sitemap, ver checker.Inventory
+	inventory := checker.RegisterInventoryFlag(flag.CommandLine, probeConfig)
+	// profiling y modo daemon, ver checker/profile.go
+	profileConfig := checker.RegisterProfileFlags(flag.CommandLine)
+	flag.IntVar(&workers, "workers", workers, "cantidad de gorutinas que verifican urls en paralelo")
+	flag.Parse()
+	if workers < 1 {
+		fmt.Fprintln(os.Stderr, "-workers debe ser al menos 1")
+		os.Exit(2)
+	}
+
+	var err error
+	if logger, err = logConfig.Logger(os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if prober, err = probeConfig.Prober(logger); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	urls, err := inventory.URLs(websites)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	profiler, err := profileConfig.Start(logger)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	logger.Info("*****comienzo *****")
+	start := time.Now()
 
-	fmt.Printf("*****comienzo *****\n")
+	// con ctrl-c se cancelan las verificaciones que faltan
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	profileConfig.Loop(ctx, func() { WebsiteStatusChecker(ctx, urls) })
 
-	WebsiteStatusChecker();
+	logger.Info("***** FIN *****", checker.KeyDuration, time.Since(start))
 
-	fmt.Printf("***** FIN *****")
+	if err := probeConfig.Close(); err != nil {
+		logger.Warn("no se pudo guardar la cache", checker.KeyError, err)
+	}
+	if err := profiler.Stop(); err != nil {
+		logger.Warn("no se pudieron guardar los perfiles", checker.KeyError, err)
+	}
 }
@@ -4,7 +4,16 @@
 
 package main
 
-import("testing")
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+
+	"arqsoft/tacticas-arq-go/performance/concurrencia/checker"
+	"arqsoft/tacticas-arq-go/performance/concurrencia/checker/checkertest"
+)
 
 
 
@@ -16,7 +25,24 @@ func BenchmarkWebsiteChecker(b *testing.B) {
 	   // hace el benchmark hasta que N que es un valor que el runtime determina para que sea significativo
 	   // Demora tenga paciencia!
 	   //
-	for i:=1 ; i < b.N; i++ {
-		WebsiteStatusChecker()
+	for i := 0; i < b.N; i++ {
+		WebsiteStatusChecker(context.Background(), websites)
+	}
+}
+
+// benchmark de escalabilidad con un prober falso, sin salir a la red: para cada cantidad de workers del fan out
+// verifica 10, 100, 1000 y 10000 urls con cada perfil de latencia de checkertest y reporta allocations, urls/s
+// y max-inflight. para guardar los resultados en el formato de benchstat
+// go test -bench Scalability -count 6 | tee resultados.txt
+func BenchmarkScalability(b *testing.B) {
+	savedLogger, savedProber, savedWorkers := logger, prober, workers
+	defer func() { logger, prober, workers = savedLogger, savedProber, savedWorkers }()
+	logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	for _, w := range []int{1, 2, 4, 8, 16} {
+		b.Run(fmt.Sprintf("workers=%d", w), func(b *testing.B) {
+			workers = w
+			checkertest.BenchmarkWorkloads(b, func(p checker.Prober) { prober = p }, WebsiteStatusChecker)
+		})
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,280 @@
+/*
+	modo crawler: en lugar de verificar una lista fija de urls, se parte de los urls iniciales, se buscan los
+	links de cada página y los que son del mismo sitio vuelven a entrar al pipeline.
+
+			           /-> gr1 (fetchPage) --\
+	frontier(coordinator) --> gr2 (fetchPage) ---> crawled --> coordinator --> sink (links rotos)
+			^          \-> grN (fetchPage) --/                 |
+			|__________________ links nuevos ___________________|
+
+	a diferencia del pipeline de website-pipeline.go el flujo no es lineal: la salida de fetchPage (los links
+	descubiertos) realimenta la entrada. eso complica saber cuándo terminar, porque el producer ya no puede
+	cerrar su canal al terminar una lista: mientras una página esté en proceso puede descubrir links nuevos.
+	la solución es que una sola gorutina (el coordinator) sea dueña del estado del crawl:
+		- el conjunto de urls visitados, para no volver a encolar un url
+		- la cola de urls pendientes, sin límite para que el coordinator nunca se bloquee al encolar (si la cola
+		  fuera un canal con buffer, los workers bloqueados enviando resultados y el coordinator bloqueado
+		  encolando se esperarían mutuamente)
+		- la cantidad de urls en vuelo (encolados o en proceso)
+	cada worker devuelve en un solo mensaje el resultado de una página junto con sus links, así cuando el
+	coordinator lo recibe ya encoló los links nuevos antes de descontar la página. cuando en vuelo llega a cero
+	no hay nada en la cola ni nadie procesando, entonces no puede aparecer ningún url más y el coordinator
+	cierra el frontier, los workers terminan y se cierra el canal de salida.
+
+	los urls del mismo host que la página que los contiene se descargan con GET y se buscan sus links, los de
+	otros hosts solo se verifican con el prober (no se sigue navegando fuera del sitio, tampoco si una página
+	redirige a otro host). se respeta la profundidad máxima (links desde los urls iniciales) y la cantidad
+	máxima de urls verificados. las páginas se descargan con las mismas tácticas que el prober (ver
+	checker.ProbeConfig.Wrap): rate limiting, circuit breaker, cache y, con -robots, no se descargan las que el
+	robots.txt del sitio no permite y se espera su Crawl-delay entre páginas.
+
+	go run . -crawl -crawl-depth=2 -crawl-max-pages=100
+*/
+
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/html"
+
+	"arqsoft/tacticas-arq-go/performance/concurrencia/checker"
+)
+
+// crawlConfig son los límites del crawl
+type crawlConfig struct {
+	// cantidad de saltos desde los urls iniciales, 0 solo verifica los iniciales
+	maxDepth int
+	// cantidad máxima de urls verificados
+	maxPages int
+	// gorutinas que descargan páginas
+	workers int
+}
+
+// máximo de bytes de una página que se leen para buscar links
+const maxPageBytes = 1 << 20
+
+// page es un url a verificar con la página que lo referencia (vacío para los iniciales)
+type page struct {
+	url      string
+	referrer string
+	depth    int
+	// crawl indica si se descarga para buscar sus links, solo para los del mismo sitio
+	crawl bool
+}
+
+// crawled es lo que cada worker le devuelve al coordinator: el resultado y los links de la página
+type crawled struct {
+	page   page
+	result checker.Result
+	links  []string
+}
+
+// pages descarga las páginas del sitio, main le agrega las mismas tácticas y configuración del prober
+var pages = pageProber(http.DefaultClient, nil)
+
+// crawl arma el pipeline con realimentación y devuelve el canal con el resultado de cada url verificado,
+// que se cierra cuando no quedan urls por verificar o se cancela ctx
+func crawl(ctx context.Context, seeds []string, cfg crawlConfig) <-chan crawled {
+	frontier := make(chan page)
+	results := make(chan crawled)
+	out := make(chan crawled)
+
+	// fan out de los workers, todos leen del frontier y escriben en results
+	var wg sync.WaitGroup
+	for i := 0; i < cfg.workers; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for p := range frontier {
+				res, links := fetchPage(ctx, p)
+				logger.Debug("url verificado", append(res.Attrs(), checker.KeyStage, "fetchPage", checker.KeyWorkerID, worker)...)
+				select {
+				case results <- crawled{page: p, result: res, links: links}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}(i)
+	}
+
+	go func() {
+		defer close(out)
+		// al terminar el coordinator se cierra el frontier y se espera a los workers, así no queda ninguna
+		// gorutina viva cuando se cierra out
+		defer wg.Wait()
+		defer close(frontier)
+		coordinate(ctx, seeds, cfg, frontier, results, out)
+	}()
+	return out
+}
+
+// coordinate es el único dueño del estado del crawl (visitados, cola y en vuelo), ver el comentario del archivo
+func coordinate(ctx context.Context, seeds []string, cfg crawlConfig, frontier chan<- page, results <-chan crawled, out chan<- crawled) {
+	visited := map[string]bool{}
+	var queue []page
+	inFlight := 0
+
+	enqueue := func(p page) {
+		if visited[p.url] || len(visited) >= cfg.maxPages {
+			return
+		}
+		visited[p.url] = true
+		queue = append(queue, p)
+		inFlight++
+	}
+	for _, seed := range seeds {
+		enqueue(page{url: seed, crawl: true})
+	}
+
+	for inFlight > 0 {
+		// el canal nil bloquea para siempre, así el select solo intenta encolar si hay algo en la cola
+		var next chan<- page
+		var head page
+		if len(queue) > 0 {
+			next = frontier
+			head = queue[0]
+		}
+
+		select {
+		case next <- head:
+			queue = queue[1:]
+
+		case c := <-results:
+			// primero se encolan los links nuevos y recién después se descuenta la página
+			if c.page.depth < cfg.maxDepth {
+				for _, link := range c.links {
+					enqueue(page{url: link, referrer: c.page.url, depth: c.page.depth + 1, crawl: sameHost(link, c.result.FinalURL)})
+				}
+			}
+			inFlight--
+
+			select {
+			case out <- c:
+			case <-ctx.Done():
+				return
+			}
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// fetchPage verifica el url y, si es del sitio y es HTML, devuelve los links que contiene
+func fetchPage(ctx context.Context, p page) (checker.Result, []string) {
+	if !p.crawl {
+		return prober.Probe(ctx, p.url), nil
+	}
+	res := pages.Probe(ctx, p.url)
+	return res, res.Links
+}
+
+// pageProber es el Prober que descarga la página con GET y deja en Result.Links los links que contiene, con
+// requests se aplican los headers, la autenticación y el User-Agent de cada url
+func pageProber(client *http.Client, requests *checker.RequestConfigs) checker.Prober {
+	return checker.ProberFunc(func(ctx context.Context, rawURL string) checker.Result {
+		res := checker.Result{URL: rawURL, Attempt: 1, Method: http.MethodGet}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+		if err != nil {
+			res.Err = err
+			return res
+		}
+		if err := requests.For(rawURL).Apply(req); err != nil {
+			res.Err = err
+			return res
+		}
+		start := time.Now()
+		response, err := client.Do(req)
+		if err != nil {
+			res.Duration = time.Since(start)
+			res.Err = err
+			return res
+		}
+		defer checker.DrainAndClose(response.Body)
+
+		res.StatusCode = response.StatusCode
+		res.FinalURL = response.Request.URL.String()
+		// si las redirecciones llevaron a otro host la página ya no es del sitio y no se siguen sus links
+		if response.StatusCode < 300 && strings.Contains(response.Header.Get("Content-Type"), "text/html") && sameHost(res.FinalURL, rawURL) {
+			res.Links = extractLinks(io.LimitReader(response.Body, maxPageBytes), response.Request.URL)
+		}
+		res.Duration = time.Since(start)
+		// una página que respondió pero con un error 4xx o 5xx es un link roto
+		res.Evaluated = true
+		if response.StatusCode >= 400 {
+			res.Unmet = []string{"link roto"}
+		}
+		return res
+	})
+}
+
+// extractLinks devuelve los href de los <a> de la página, resueltos contra el url de la página y normalizados
+func extractLinks(body io.Reader, base *url.URL) []string {
+	var links []string
+	tokenizer := html.NewTokenizer(body)
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return links
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := tokenizer.TagName()
+			if string(name) != "a" {
+				continue
+			}
+			for hasAttr {
+				var key, value []byte
+				key, value, hasAttr = tokenizer.TagAttr()
+				if string(key) != "href" {
+					continue
+				}
+				ref, err := base.Parse(strings.TrimSpace(string(value)))
+				if err != nil {
+					continue
+				}
+				// mailto:, javascript:, etc. no son páginas a verificar
+				if link, err := checker.Normalize(ref.String()); err == nil && strings.HasPrefix(link, "http") {
+					links = append(links, link)
+				}
+			}
+		}
+	}
+}
+
+func sameHost(a, b string) bool {
+	return checker.Host(a) == checker.Host(b)
+}
+
+// Crawl recorre el sitio a partir de los urls iniciales y registra los links rotos con la página que los referencia,
+// al cancelar ctx (ctrl-c) deja de encolar urls y espera a que terminen los workers
+func Crawl(ctx context.Context, urls []string, cfg crawlConfig) {
+	prepared := checker.PrepareURLs(urls)
+	prepared.Log(logger)
+
+	var summary checker.Summary
+	summary.Add(prepared.Invalid...)
+	defer summary.Log(logger)
+
+	for c := range crawl(ctx, prepared.URLs, cfg) {
+		summary.Add(c.result)
+		attrs := append(c.result.Attrs(), checker.KeyStage, "sink", checker.KeyDepth, c.page.depth)
+		if c.page.referrer != "" {
+			attrs = append(attrs, checker.KeyReferrer, checker.RedactURL(c.page.referrer))
+		}
+		switch {
+		case c.result.OK():
+			logger.Info("link ok", attrs...)
+		case c.result.Skipped():
+			// por ejemplo un url que el robots.txt no permite, no se sabe si está roto
+			logger.Info("link no verificado", attrs...)
+		default:
+			logger.Warn("link roto", attrs...)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"arqsoft/tacticas-arq-go/performance/concurrencia/checker"
+	"arqsoft/tacticas-arq-go/performance/concurrencia/checker/checkertest"
+)
+
+// newSite arma un sitio de prueba: / lleva a /a y a /b, /a vuelve a / (un ciclo) y lleva a /a/deep,
+// /b tiene un link roto y un link a un servidor externo que rechaza las conexiones
+func newSite(t *testing.T) *httptest.Server {
+	t.Helper()
+	ln, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// con el nombre localhost el link es de otro host que el del sitio (127.0.0.1)
+	external := fmt.Sprintf("http://localhost:%d/", ln.Addr().(*net.TCPAddr).Port)
+	ln.Close()
+
+	pages := map[string]string{
+		"/":       `<a href="/a">a</a> <a href="b">b</a> <a href="mailto:x@example.com">mail</a>`,
+		"/a":      `<a href="/">inicio</a> <a href="/a/deep">deep</a>`,
+		"/a/deep": `<p>sin links</p>`,
+		"/b":      fmt.Sprintf(`<a href="/missing">roto</a> <a href=%q>externo</a>`, external),
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// runCrawl devuelve lo que llegó al sink indexado por url, falla si el crawl no termina. el url inicial
+// tiene que estar normalizado (sin la / final) como los que devuelve PrepareURLs
+func runCrawl(t *testing.T, seed string, cfg crawlConfig) map[string]crawled {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	got := map[string]crawled{}
+	for c := range crawl(ctx, []string{seed}, cfg) {
+		if _, dup := got[c.page.url]; dup {
+			t.Errorf("%s se verificó más de una vez", c.page.url)
+		}
+		got[c.page.url] = c
+	}
+	if ctx.Err() != nil {
+		t.Fatal("el crawl no terminó")
+	}
+	return got
+}
+
+func TestCrawlFollowsLinksAndReportsBrokenOnes(t *testing.T) {
+	srv := newSite(t)
+	got := runCrawl(t, srv.URL, crawlConfig{maxDepth: 3, maxPages: 100, workers: 3})
+
+	if len(got) != 6 {
+		t.Fatalf("se esperaban 6 urls (/, /a, /b, /a/deep, /missing y el externo) y se verificaron %d: %v", len(got), got)
+	}
+	for _, path := range []string{"", "/a", "/b", "/a/deep"} {
+		if c, ok := got[srv.URL+path]; !ok || !c.result.OK() {
+			t.Errorf("%s debería estar ok: %+v", path, c.result)
+		}
+	}
+
+	missing := got[srv.URL+"/missing"]
+	if missing.result.OK() || missing.result.StatusCode != http.StatusNotFound {
+		t.Errorf("/missing debería ser un link roto con 404: %+v", missing.result)
+	}
+	if missing.page.referrer != srv.URL+"/b" {
+		t.Errorf("el referrer de /missing debería ser /b y es %q", missing.page.referrer)
+	}
+
+	for url, c := range got {
+		if c.page.crawl {
+			continue
+		}
+		if c.result.OK() || c.page.referrer != srv.URL+"/b" {
+			t.Errorf("el link externo %s debería fallar y venir de /b: %+v", url, c)
+		}
+	}
+}
+
+func TestCrawlRespectsDepthAndMaxPages(t *testing.T) {
+	srv := newSite(t)
+
+	if got := runCrawl(t, srv.URL, crawlConfig{maxDepth: 0, maxPages: 100, workers: 2}); len(got) != 1 {
+		t.Errorf("con profundidad 0 solo se verifica el url inicial y se verificaron %d", len(got))
+	}
+	got := runCrawl(t, srv.URL, crawlConfig{maxDepth: 1, maxPages: 100, workers: 2})
+	if len(got) != 3 {
+		t.Errorf("con profundidad 1 se verifican /, /a y /b y se verificaron %d", len(got))
+	}
+	if got := runCrawl(t, srv.URL, crawlConfig{maxDepth: 3, maxPages: 2, workers: 2}); len(got) != 2 {
+		t.Errorf("con máximo 2 se verificaron %d urls", len(got))
+	}
+}
+
+func TestCrawlStopsOnCancel(t *testing.T) {
+	srv := newSite(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	out := crawl(ctx, []string{srv.URL}, crawlConfig{maxDepth: 3, maxPages: 100, workers: 2})
+	<-out
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		for range out {
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("el canal de salida no se cerró al cancelar")
+	}
+}
+
+// Crawl tiene que terminar al cancelar ctx (ctrl-c o el fin de -daemon) aunque las páginas no respondan,
+// sin dejar gorutinas vivas
+func TestCrawlReturnsOnCancelWithoutLeaks(t *testing.T) {
+	checkertest.CheckLeaks(t)
+	savedLogger := logger
+	t.Cleanup(func() { logger = savedLogger })
+	var logs *checkertest.Logs
+	logger, logs = checkertest.NewLogs()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			// las páginas enlazadas no responden hasta que se cancela el pedido
+			<-r.Context().Done()
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		for i := 0; i < 10; i++ {
+			fmt.Fprintf(w, `<a href="/lenta/%d">%d</a>`, i, i)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		Crawl(ctx, []string{srv.URL}, crawlConfig{maxDepth: 2, maxPages: 100, workers: 3})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Crawl no terminó al cancelar el context")
+	}
+	if logs.Find("resumen") == nil {
+		t.Error("al cancelar también se registra el resumen")
+	}
+}
+
+// con un url inicial con usuario y contraseña los links del sitio los heredan, la contraseña no tiene que
+// aparecer en los registros de las páginas ni en el de la página que las referencia
+func TestCrawlRedactsPasswords(t *testing.T) {
+	savedLogger := logger
+	t.Cleanup(func() { logger = savedLogger })
+	logs := &checkertest.Logs{}
+	logger = slog.New(slog.NewJSONHandler(logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	srv := newSite(t)
+	seed := strings.Replace(srv.URL, "http://", "http://admin:s3cr3tpw@", 1)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	Crawl(ctx, []string{seed}, crawlConfig{maxDepth: 3, maxPages: 100, workers: 2})
+
+	if logs.Find("link roto") == nil {
+		t.Fatal("no se registró el link roto del sitio")
+	}
+	if strings.Contains(logs.String(), "s3cr3tpw") {
+		t.Errorf("la contraseña aparece en los registros:\n%s", logs.String())
+	}
+}
+
+// las páginas del sitio se descargan con pages, al que main le agrega las tácticas del prober
+func TestCrawlFetchesPagesThroughTheProberTactics(t *testing.T) {
+	srv := newSite(t)
+	saved := pages
+	t.Cleanup(func() { pages = saved })
+	var fetched atomic.Int32
+	pages = checker.Chain(pageProber(http.DefaultClient, nil), func(next checker.Prober) checker.Prober {
+		return checker.ProberFunc(func(ctx context.Context, url string) checker.Result {
+			fetched.Add(1)
+			return next.Probe(ctx, url)
+		})
+	})
+
+	runCrawl(t, srv.URL, crawlConfig{maxDepth: 3, maxPages: 100, workers: 2})
+	// /, /a, /b, /a/deep y /missing, el link externo lo verifica el prober
+	if fetched.Load() != 5 {
+		t.Errorf("se descargaron %d páginas a través de las tácticas, se esperaban 5", fetched.Load())
+	}
+}
+
+// una página del sitio que redirige a otro host ya no es del sitio y no se siguen sus links
+func TestCrawlDoesNotFollowLinksAfterRedirectToOtherHost(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch {
+		case r.URL.Path == "/":
+			fmt.Fprint(w, `<a href="/salir">salir</a>`)
+		case r.URL.Path == "/salir":
+			// el mismo servidor con el nombre localhost es otro host
+			http.Redirect(w, r, strings.Replace(srv.URL, "127.0.0.1", "localhost", 1)+"/otro", http.StatusFound)
+		case r.URL.Path == "/otro":
+			fmt.Fprint(w, `<a href="/secreto">secreto</a>`)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	got := runCrawl(t, srv.URL, crawlConfig{maxDepth: 3, maxPages: 100, workers: 2})
+	if len(got) != 2 {
+		t.Errorf("se esperaban / y /salir y se verificaron %d: %v", len(got), got)
+	}
+	for url := range got {
+		if strings.HasSuffix(url, "/secreto") {
+			t.Errorf("se siguió un link de la página de otro host: %s", url)
+		}
+	}
+}
@@ -0,0 +1,287 @@
+/*
+	inyección de fallas en las etapas del pipeline.
+
+	para ver cómo se comporta el manejo de errores del pipeline no hace falta esperar a que un sitio real falle:
+	con -faults=fallas.json se agrega delante de cada etapa (checkWebsite, convertResultaToUpperCase y sink) un
+	filtro que, según la configuración de la etapa, le hace a cada item una de estas cosas:
+		- latency y jitter: lo demora latency más un tiempo al azar de hasta jitter
+		- drop_rate: lo descarta, el url no llega al sink ni al resumen
+		- error_rate: en lugar de pasarlo a la etapa manda un error por el canal de errores
+		- panic_rate: hace panic en la gorutina del filtro, el supervisor la recupera (ver supervisor.go)
+		- stall_rate: se queda bloqueado stall (o hasta que se cancele el context si no se indica)
+
+		{
+		  "seed": 42,
+		  "stages": {
+		    "checkWebsite": {"latency": "50ms", "jitter": "100ms", "error_rate": 0.2},
+		    "sink": {"drop_rate": 0.1, "stall_rate": 0.05, "stall": "2s"}
+		  }
+		}
+
+	las ejecuciones tienen que ser reproducibles para poder mostrar una falla en una revisión y volver a verla.
+	con un único generador de números al azar no alcanza: las gorutinas lo consultan en otro orden en cada
+	ejecución. por eso la decisión de cada item se calcula con un generador propio cuya semilla sale de la seed,
+	la etapa y el url, y con la misma seed el mismo url sufre la misma falla en la misma etapa siempre.
+	-fault-seed cambia la seed del archivo.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"os"
+	"time"
+
+	"arqsoft/tacticas-arq-go/performance/concurrencia/checker"
+)
+
+// fallas que se pueden inyectar en un item, además de la latencia
+type faultKind int
+
+const (
+	noFault faultKind = iota
+	dropFault
+	errorFault
+	panicFault
+	stallFault
+)
+
+var faultNames = map[faultKind]string{noFault: "none", dropFault: "drop", errorFault: "error", panicFault: "panic", stallFault: "stall"}
+
+func (k faultKind) String() string {
+	return faultNames[k]
+}
+
+// errFaultInjected es el error de los items a los que se les inyectó un error
+var errFaultInjected = errors.New("falla inyectada")
+
+// stageFaults es la configuración de las fallas de una etapa, las tasas son probabilidades entre 0 y 1
+type stageFaults struct {
+	Latency   time.Duration
+	Jitter    time.Duration
+	DropRate  float64
+	ErrorRate float64
+	PanicRate float64
+	StallRate float64
+	Stall     time.Duration
+}
+
+func (f *stageFaults) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Latency   string  `json:"latency"`
+		Jitter    string  `json:"jitter"`
+		DropRate  float64 `json:"drop_rate"`
+		ErrorRate float64 `json:"error_rate"`
+		PanicRate float64 `json:"panic_rate"`
+		StallRate float64 `json:"stall_rate"`
+		Stall     string  `json:"stall"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*f = stageFaults{DropRate: raw.DropRate, ErrorRate: raw.ErrorRate, PanicRate: raw.PanicRate, StallRate: raw.StallRate}
+	for _, d := range []struct {
+		name  string
+		value string
+		to    *time.Duration
+	}{{"latency", raw.Latency, &f.Latency}, {"jitter", raw.Jitter, &f.Jitter}, {"stall", raw.Stall, &f.Stall}} {
+		if d.value == "" {
+			continue
+		}
+		var err error
+		if *d.to, err = time.ParseDuration(d.value); err != nil {
+			return fmt.Errorf("%s inválido: %w", d.name, err)
+		}
+	}
+	for _, rate := range []struct {
+		name  string
+		value float64
+	}{{"drop_rate", f.DropRate}, {"error_rate", f.ErrorRate}, {"panic_rate", f.PanicRate}, {"stall_rate", f.StallRate}} {
+		// una tasa negativa restaría probabilidad a las demás al decidir la falla
+		if rate.value < 0 || rate.value > 1 {
+			return fmt.Errorf("%s es %g, tiene que estar entre 0 y 1", rate.name, rate.value)
+		}
+	}
+	if total := f.DropRate + f.ErrorRate + f.PanicRate + f.StallRate; total > 1 {
+		return fmt.Errorf("la suma de las tasas es %.2f, no puede ser mayor que 1", total)
+	}
+	return nil
+}
+
+// faultInjector decide qué falla sufre cada item en cada etapa.
+// un faultInjector nil es válido y no inyecta nada, igual que el Tracer
+type faultInjector struct {
+	seed   int64
+	stages map[string]stageFaults
+}
+
+// inyector de la ejecución, queda en nil si no se pasa el flag -faults
+var faults *faultInjector
+
+// loadFaults lee la configuración de fallas, una seed distinta de 0 reemplaza a la del archivo
+func loadFaults(path string, seed int64) (*faultInjector, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Seed   int64                  `json:"seed"`
+		Stages map[string]stageFaults `json:"stages"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for stage := range file.Stages {
+		switch stage {
+		case "checkWebsite", "convertResultaToUpperCase", "sink":
+		default:
+			return nil, fmt.Errorf("%s: la etapa %q no existe", path, stage)
+		}
+	}
+	if seed != 0 {
+		file.Seed = seed
+	}
+	return &faultInjector{seed: file.Seed, stages: file.Stages}, nil
+}
+
+// enabled indica si hay fallas configuradas para la etapa
+func (f *faultInjector) enabled(stage string) bool {
+	if f == nil {
+		return false
+	}
+	_, ok := f.stages[stage]
+	return ok
+}
+
+// decide devuelve la falla y la demora del url en la etapa, siempre las mismas para la misma seed
+func (f *faultInjector) decide(stage, url string) (faultKind, time.Duration) {
+	cfg, ok := f.stages[stage]
+	if !ok {
+		return noFault, 0
+	}
+	h := fnv.New64a()
+	h.Write([]byte(stage + "\x00" + url))
+	rng := rand.New(rand.NewSource(f.seed ^ int64(h.Sum64())))
+
+	delay := cfg.Latency
+	if cfg.Jitter > 0 {
+		delay += time.Duration(rng.Int63n(int64(cfg.Jitter)))
+	}
+
+	// una sola tirada para elegir la falla, cada tasa es un tramo del intervalo [0, 1)
+	p := rng.Float64()
+	for _, fault := range []struct {
+		kind faultKind
+		rate float64
+	}{{dropFault, cfg.DropRate}, {errorFault, cfg.ErrorRate}, {panicFault, cfg.PanicRate}, {stallFault, cfg.StallRate}} {
+		if p < fault.rate {
+			return fault.kind, delay
+		}
+		p -= fault.rate
+	}
+	return noFault, delay
+}
+
+// filter es el worker del filtro que se agrega delante de una etapa: aplica a cada item que sale de in la falla
+// que le toca y pasa los que sobreviven a out. los errores inyectados salen por el canal de errores
+func (f *faultInjector) filter(stage string) worker {
+	return func(ctx context.Context, in <-chan item, out chan<- item, errorChannel chan<- error, worker int) {
+		for it := range in {
+			fault, delay := f.decide(stage, it.url)
+			if !sleep(ctx, delay) {
+				return
+			}
+			if fault != noFault {
+				logger.Debug("falla inyectada", checker.KeyURL, it.url, checker.KeyStage, stage, checker.KeyWorkerID, worker, "fault", fault.String())
+			}
+
+			switch fault {
+			case dropFault:
+				// el item se pierde, su traza termina acá
+				it.span.RecordError(errFaultInjected)
+				it.span.End()
+				continue
+			case errorFault:
+				res := it.result
+				res.URL = it.url
+				res.Err = errFaultInjected
+				it.span.RecordError(errFaultInjected)
+				it.span.End()
+				select {
+				case errorChannel <- &stageError{stage: stage, worker: worker, message: "falla inyectada", result: res}:
+				case <-ctx.Done():
+					return
+				}
+				continue
+			case panicFault:
+				panic(fmt.Sprintf("falla inyectada en %s: %s", stage, checker.RedactURL(it.url)))
+			case stallFault:
+				cfg := f.stages[stage]
+				if cfg.Stall <= 0 {
+					// bloqueado hasta que se cancele el pipeline
+					<-ctx.Done()
+					return
+				}
+				if !sleep(ctx, cfg.Stall) {
+					return
+				}
+			}
+
+			select {
+			case out <- it:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// sleep espera d o hasta que se cancele ctx, devuelve false si se canceló
+func sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// withFaults agrega delante de una etapa el filtro de fallas, supervisado como los workers de la etapa.
+// si no hay fallas configuradas devuelve la etapa tal cual
+func withFaults(sup *supervisor, name string, w worker) stage {
+	s := sup.stage(name, w)
+	if !faults.enabled(name) {
+		return s
+	}
+	filter := sup.stage(name, faults.filter(name))
+	return func(ctx context.Context, in <-chan item, worker int) (<-chan item, <-chan error, error) {
+		filtered, injected, err := filter(ctx, in, worker)
+		if err != nil {
+			return nil, nil, err
+		}
+		out, errs, err := s(ctx, filtered, worker)
+		if err != nil {
+			return nil, nil, err
+		}
+		return out, mergeErrorChans(ctx, injected, errs), nil
+	}
+}
+
+// sinkWithFaults aplica al canal de entrada del sink las fallas configuradas para "sink"
+func sinkWithFaults(ctx context.Context, sup *supervisor, in <-chan item) (<-chan item, <-chan error) {
+	if !faults.enabled("sink") {
+		return in, nil
+	}
+	out, errs, _ := sup.stage("sink", faults.filter("sink"))(ctx, in, 0)
+	return out, errs
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// con la misma seed cada url sufre siempre la misma falla, con otra seed cambian
+func TestFaultDecisionsAreDeterministic(t *testing.T) {
+	cfg := map[string]stageFaults{"checkWebsite": {Jitter: 10 * time.Millisecond, DropRate: 0.3, ErrorRate: 0.3}}
+	a := &faultInjector{seed: 42, stages: cfg}
+	b := &faultInjector{seed: 42, stages: cfg}
+	other := &faultInjector{seed: 7, stages: cfg}
+
+	changed := false
+	for i := 0; i < 100; i++ {
+		url := fmt.Sprintf("http://example.com/%d", i)
+		kindA, delayA := a.decide("checkWebsite", url)
+		kindB, delayB := b.decide("checkWebsite", url)
+		if kindA != kindB || delayA != delayB {
+			t.Fatalf("%s: con la misma seed se obtuvo %s/%v y %s/%v", url, kindA, delayA, kindB, delayB)
+		}
+		if kind, delay := other.decide("checkWebsite", url); kind != kindA || delay != delayA {
+			changed = true
+		}
+	}
+	if !changed {
+		t.Error("con otra seed se obtuvieron las mismas fallas para los 100 urls")
+	}
+}
+
+// las tasas se respetan en promedio
+func TestFaultRates(t *testing.T) {
+	f := &faultInjector{seed: 1, stages: map[string]stageFaults{"sink": {DropRate: 0.1, ErrorRate: 0.2, StallRate: 0.3}}}
+	const n = 10000
+	counts := map[faultKind]int{}
+	for i := 0; i < n; i++ {
+		kind, _ := f.decide("sink", fmt.Sprintf("http://example.com/%d", i))
+		counts[kind]++
+	}
+	for kind, rate := range map[faultKind]float64{dropFault: 0.1, errorFault: 0.2, stallFault: 0.3, noFault: 0.4, panicFault: 0} {
+		got := float64(counts[kind]) / n
+		if got < rate-0.02 || got > rate+0.02 {
+			t.Errorf("%s: tasa %.3f, se esperaba %.2f", kind, got, rate)
+		}
+	}
+}
+
+// el filtro descarta, convierte en errores y demora los items según la configuración
+func TestFaultFilter(t *testing.T) {
+	f := &faultInjector{seed: 3, stages: map[string]stageFaults{"checkWebsite": {Latency: 5 * time.Millisecond, DropRate: 0.25, ErrorRate: 0.25}}}
+	in := make(chan item)
+	const n = 40
+	urls := make([]string, n)
+	want := map[faultKind]int{}
+	for i := range urls {
+		urls[i] = fmt.Sprintf("http://example.com/%d", i)
+		kind, _ := f.decide("checkWebsite", urls[i])
+		want[kind]++
+	}
+	go func() {
+		defer close(in)
+		for _, url := range urls {
+			in <- item{url: url, value: url}
+		}
+	}()
+
+	start := time.Now()
+	out, errs, _ := newSupervisor(restartPolicy{}).stage("checkWebsite", f.filter("checkWebsite"))(context.Background(), in, 0)
+	passed, failed := 0, 0
+	for out != nil || errs != nil {
+		select {
+		case _, ok := <-out:
+			if !ok {
+				out = nil
+				continue
+			}
+			passed++
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			if !errors.Is(err, errFaultInjected) {
+				t.Errorf("el error %v no es errFaultInjected", err)
+			}
+			failed++
+		}
+	}
+
+	if passed != want[noFault] || failed != want[errorFault] {
+		t.Errorf("pasaron %d y fallaron %d, se esperaban %d y %d", passed, failed, want[noFault], want[errorFault])
+	}
+	if elapsed := time.Since(start); elapsed < n*5*time.Millisecond {
+		t.Errorf("el filtro demoró %v, con la latencia no puede ser menos de %v", elapsed, n*5*time.Millisecond)
+	}
+}
+
+// un item bloqueado sin límite se libera al cancelar el pipeline
+func TestFaultFilterStallEndsOnCancel(t *testing.T) {
+	f := &faultInjector{stages: map[string]stageFaults{"sink": {StallRate: 1}}}
+	in := make(chan item, 1)
+	in <- item{url: "http://example.com"}
+	close(in)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	out, _, _ := newSupervisor(restartPolicy{}).stage("sink", f.filter("sink"))(ctx, in, 0)
+	time.AfterFunc(10*time.Millisecond, cancel)
+	select {
+	case _, ok := <-out:
+		if ok {
+			t.Error("el item bloqueado no debería salir del filtro")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("el filtro no terminó al cancelar el context")
+	}
+}
+
+// sin -faults las etapas quedan tal cual
+func TestWithFaultsDisabled(t *testing.T) {
+	saved := faults
+	faults = nil
+	defer func() { faults = saved }()
+
+	in := make(chan item)
+	if out, errs := sinkWithFaults(context.Background(), newSupervisor(restartPolicy{}), in); out != (<-chan item)(in) || errs != nil {
+		t.Error("sin fallas el sink debe recibir el mismo canal y ningún canal de errores")
+	}
+}
+
+func TestLoadFaults(t *testing.T) {
+	write := func(content string) string {
+		path := filepath.Join(t.TempDir(), "fallas.json")
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	f, err := loadFaults(write(`{"seed": 42, "stages": {"checkWebsite": {"latency": "50ms", "jitter": "1s", "error_rate": 0.2}}}`), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := f.stages["checkWebsite"]; f.seed != 42 || got.Latency != 50*time.Millisecond || got.Jitter != time.Second || got.ErrorRate != 0.2 {
+		t.Errorf("configuración leída %+v, seed %d", got, f.seed)
+	}
+	if f, _ := loadFaults(write(`{"seed": 42}`), 9); f.seed != 9 {
+		t.Errorf("-fault-seed debe reemplazar la seed del archivo, quedó %d", f.seed)
+	}
+
+	for name, tc := range map[string]struct{ content, want string }{
+		"etapa":    {`{"stages": {"parse": {}}}`, `"parse" no existe`},
+		"tasas":    {`{"stages": {"sink": {"drop_rate": 0.6, "error_rate": 0.6}}}`, "no puede ser mayor que 1"},
+		"negativa": {`{"stages": {"sink": {"drop_rate": -0.5, "error_rate": 0.5}}}`, "drop_rate es -0.5, tiene que estar entre 0 y 1"},
+		"mayor":    {`{"stages": {"checkWebsite": {"panic_rate": 1.5}}}`, "panic_rate es 1.5"},
+		"duración": {`{"stages": {"sink": {"latency": "rápido"}}}`, "latency inválido"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := loadFaults(write(tc.content), 0)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("se esperaba un error con %q y se obtuvo %v", tc.want, err)
+			}
+		})
+	}
+}
@@ -1,3 +1,18 @@
 module arqsoft/tacticas-arq-go/performance/concurrencia/pipes-filters
 
-go 1.18
+go 1.21
+
+require (
+	arqsoft/tacticas-arq-go/performance/concurrencia/checker v0.0.0
+	golang.org/x/net v0.25.0
+)
+
+require (
+	golang.org/x/sys v0.20.0 // indirect
+	golang.org/x/text v0.15.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20240528184218-531527333157 // indirect
+	google.golang.org/grpc v1.65.0 // indirect
+	google.golang.org/protobuf v1.34.1 // indirect
+)
+
+replace arqsoft/tacticas-arq-go/performance/concurrencia/checker => ../checker
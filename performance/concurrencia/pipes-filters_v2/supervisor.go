@@ -0,0 +1,151 @@
+/*
+	supervisión de los workers del pipeline: recuperación de panics y reinicio.
+
+	un panic en una gorutina que no lo recupera termina todo el proceso, no solo la gorutina. si callHead o un
+	filtro hacen panic con un url (un bug, una respuesta que no se esperaba) se pierde la verificación de todos
+	los demás. es una táctica de disponibilidad: cada worker de una etapa corre bajo un supervisor que
+		- recupera el panic y lo convierte en un error (con el stack trace) por el canal de errores de la etapa,
+		  así llega al sink como cualquier otro error
+		- reinicia solo al worker que falló, los demás siguen trabajando (la estrategia one-for-one de Erlang/OTP).
+		  el worker nuevo sigue leyendo del mismo canal, el item que causó el panic se pierde
+		- lleva un presupuesto de reinicios: como mucho -max-restarts en -restart-window entre todos los workers.
+		  si se agota el problema no es un url sino la etapa, y reiniciar sin fin solo lo esconde; el supervisor
+		  deja de reiniciar y escala la falla, el sink cancela el pipeline (ver sink)
+
+	sin -max-restarts (0) los panics se recuperan pero el primero cancela el pipeline, que termina prolijamente.
+	con -faults se pueden provocar panics para verlo (panic_rate, ver faults.go):
+
+		go run . -faults=fallas.json -max-restarts=5 -restart-window=1m
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"arqsoft/tacticas-arq-go/performance/concurrencia/checker"
+)
+
+// worker es el cuerpo de una gorutina de una etapa: procesa los items de in hasta que se cierre o se cancele
+// ctx. no cierra out ni errs, de eso se encarga el supervisor, que puede volver a llamarlo si hace panic
+type worker func(ctx context.Context, in <-chan item, out chan<- item, errs chan<- error, id int)
+
+// stage es la firma de las etapas del pipeline: lanzan el worker y devuelven sus canales de salida y de errores
+type stage func(ctx context.Context, in <-chan item, worker int) (<-chan item, <-chan error, error)
+
+// restartPolicy es el presupuesto de reinicios: MaxRestarts en Window, con Window 0 en toda la ejecución
+type restartPolicy struct {
+	MaxRestarts int
+	Window      time.Duration
+}
+
+// política de reinicio de los workers, main la configura con -max-restarts y -restart-window
+var restarts restartPolicy
+
+// panicError es el error de un worker que hizo panic
+type panicError struct {
+	stage  string
+	worker int
+	value  any
+	stack  []byte
+	// restarted indica si el supervisor reinició al worker, si es false se agotaron los reinicios
+	restarted bool
+}
+
+func (e *panicError) Error() string {
+	return fmt.Sprintf("panic en %s (worker %d): %v", e.stage, e.worker, e.value)
+}
+
+// escalated devuelve el panic de un worker que el supervisor no reinició, nil si err no es uno
+func escalated(err error) *panicError {
+	var panicErr *panicError
+	if errors.As(err, &panicErr) && !panicErr.restarted {
+		return panicErr
+	}
+	return nil
+}
+
+// supervisor lanza los workers de las etapas y los reinicia con el presupuesto de la política
+type supervisor struct {
+	policy restartPolicy
+
+	mu sync.Mutex
+	// momentos de los reinicios dentro de la ventana
+	history []time.Time
+}
+
+func newSupervisor(policy restartPolicy) *supervisor {
+	return &supervisor{policy: policy}
+}
+
+// stage arma la etapa del pipeline con el worker supervisado, con la misma firma de los filtros
+func (s *supervisor) stage(name string, w worker) stage {
+	return func(ctx context.Context, in <-chan item, id int) (<-chan item, <-chan error, error) {
+		out := make(chan item)
+		errs := make(chan error)
+
+		go func() {
+			defer close(out)
+			defer close(errs)
+
+			for {
+				err := run(ctx, name, id, w, in, out, errs)
+				if err == nil {
+					// el worker terminó porque se cerró in o se canceló ctx
+					return
+				}
+				err.restarted = s.allowRestart()
+				select {
+				case errs <- err:
+				case <-ctx.Done():
+					return
+				}
+				if !err.restarted {
+					return
+				}
+				logger.Warn("se reinicia el worker", checker.KeyStage, name, checker.KeyWorkerID, id)
+			}
+		}()
+
+		return out, errs, nil
+	}
+}
+
+// run ejecuta el worker y devuelve el panic como error, nil si terminó normalmente
+func run(ctx context.Context, name string, id int, w worker, in <-chan item, out chan<- item, errs chan<- error) (err *panicError) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = &panicError{stage: name, worker: id, value: v, stack: debug.Stack()}
+		}
+	}()
+	w(ctx, in, out, errs, id)
+	return nil
+}
+
+// allowRestart registra un reinicio si queda presupuesto en la ventana
+func (s *supervisor) allowRestart() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if s.policy.Window > 0 {
+		// se descartan los reinicios que ya salieron de la ventana
+		recent := s.history[:0]
+		for _, t := range s.history {
+			if now.Sub(t) < s.policy.Window {
+				recent = append(recent, t)
+			}
+		}
+		s.history = recent
+	}
+	if len(s.history) >= s.policy.MaxRestarts {
+		return false
+	}
+	s.history = append(s.history, now)
+	return true
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"arqsoft/tacticas-arq-go/performance/concurrencia/checker"
+)
+
+// panicky es un worker que pasa los items tal cual y hace panic con los urls que terminan en /panic
+func panicky(ctx context.Context, in <-chan item, out chan<- item, errs chan<- error, id int) {
+	for it := range in {
+		if strings.HasSuffix(it.url, "/panic") {
+			panic("no se esperaba " + it.url)
+		}
+		out <- it
+	}
+}
+
+// collect lee la salida y los errores de una etapa hasta que se cierran los dos canales
+func collect(t *testing.T, out <-chan item, errs <-chan error) (items []item, stageErrs []error) {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for out != nil || errs != nil {
+		select {
+		case it, ok := <-out:
+			if !ok {
+				out = nil
+				continue
+			}
+			items = append(items, it)
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			stageErrs = append(stageErrs, err)
+		case <-timeout:
+			t.Fatal("la etapa no terminó")
+		}
+	}
+	return items, stageErrs
+}
+
+func feed(urls ...string) <-chan item {
+	in := make(chan item, len(urls))
+	for _, url := range urls {
+		in <- item{url: url, value: url}
+	}
+	close(in)
+	return in
+}
+
+// el panic llega como error con el stack y el worker reiniciado sigue con el resto de los items
+func TestSupervisorRecoversAndRestarts(t *testing.T) {
+	sup := newSupervisor(restartPolicy{MaxRestarts: 2})
+	in := feed("http://a", "http://b/panic", "http://c", "http://d/panic", "http://e")
+	out, errs, _ := sup.stage("test", panicky)(context.Background(), in, 7)
+
+	items, stageErrs := collect(t, out, errs)
+	if len(items) != 3 {
+		t.Errorf("pasaron %d items, se esperaban los 3 que no hacen panic", len(items))
+	}
+	if len(stageErrs) != 2 {
+		t.Fatalf("llegaron %d errores, se esperaban 2", len(stageErrs))
+	}
+	for _, err := range stageErrs {
+		var panicErr *panicError
+		if !errors.As(err, &panicErr) {
+			t.Fatalf("el error %v no es un panicError", err)
+		}
+		if panicErr.stage != "test" || panicErr.worker != 7 || !panicErr.restarted {
+			t.Errorf("panicError inesperado %+v", panicErr)
+		}
+		if !bytes.Contains(panicErr.stack, []byte("panicky")) {
+			t.Errorf("el stack no muestra dónde fue el panic:\n%s", panicErr.stack)
+		}
+		if escalated(err) != nil {
+			t.Error("un panic con reinicio no debe escalar")
+		}
+	}
+}
+
+// con el presupuesto agotado el worker no se reinicia y el error escala
+func TestSupervisorGivesUpWhenBudgetIsExhausted(t *testing.T) {
+	sup := newSupervisor(restartPolicy{MaxRestarts: 1})
+	in := feed("http://a/panic", "http://b/panic", "http://c")
+	out, errs, _ := sup.stage("test", panicky)(context.Background(), in, 0)
+
+	items, stageErrs := collect(t, out, errs)
+	if len(items) != 0 {
+		t.Errorf("el worker terminó y aún así pasaron %d items", len(items))
+	}
+	if len(stageErrs) != 2 {
+		t.Fatalf("llegaron %d errores, se esperaban 2", len(stageErrs))
+	}
+	if escalated(stageErrs[0]) != nil {
+		t.Error("el primer panic entra en el presupuesto y no debe escalar")
+	}
+	if escalated(stageErrs[1]) == nil {
+		t.Error("el segundo panic agota el presupuesto y debe escalar")
+	}
+}
+
+// los reinicios que salieron de la ventana no cuentan
+func TestSupervisorRestartWindow(t *testing.T) {
+	sup := newSupervisor(restartPolicy{MaxRestarts: 1, Window: 20 * time.Millisecond})
+	if !sup.allowRestart() {
+		t.Fatal("el primer reinicio entra en el presupuesto")
+	}
+	if sup.allowRestart() {
+		t.Fatal("el segundo reinicio dentro de la ventana no entra en el presupuesto")
+	}
+	time.Sleep(30 * time.Millisecond)
+	if !sup.allowRestart() {
+		t.Error("pasada la ventana el reinicio debe estar permitido")
+	}
+}
+
+// summaryOf ejecuta el pipeline con un prober que hace panic con los urls que terminan en /panic y devuelve
+// el resumen que registra al final
+func summaryOf(t *testing.T, policy restartPolicy, urls []string) map[string]any {
+	t.Helper()
+	var buf bytes.Buffer
+	savedLogger, savedProber, savedRestarts := logger, prober, restarts
+	defer func() { logger, prober, restarts = savedLogger, savedProber, savedRestarts }()
+	logger = slog.New(slog.NewJSONHandler(&buf, nil))
+	restarts = policy
+	prober = checker.ProberFunc(func(ctx context.Context, url string) checker.Result {
+		if strings.HasSuffix(url, "/panic") {
+			panic("no se esperaba " + url)
+		}
+		return checker.Result{URL: url, StatusCode: 200, Evaluated: true}
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		WebsiteStatusChecker(context.Background(), urls)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("el pipeline no terminó")
+	}
+
+	for _, line := range bytes.Split(buf.Bytes(), []byte("\n")) {
+		var record map[string]any
+		if json.Unmarshal(line, &record) == nil && record["msg"] == "resumen" {
+			return record
+		}
+	}
+	t.Fatalf("no se registró el resumen:\n%s", buf.String())
+	return nil
+}
+
+// un panic en callHead no termina el proceso: con presupuesto se verifican los demás urls
+// y sin presupuesto el pipeline se cancela y termina
+func TestPipelineSurvivesPanics(t *testing.T) {
+	var urls []string
+	for i := 0; i < 10; i++ {
+		urls = append(urls, fmt.Sprintf("http://example.com/%d", i))
+	}
+	urls = append(urls, "http://example.com/panic")
+
+	summary := summaryOf(t, restartPolicy{MaxRestarts: 5}, urls)
+	if summary["ok"] != float64(10) {
+		t.Errorf("con reinicios se esperaban 10 urls ok, el resumen es %v", summary)
+	}
+
+	summaryOf(t, restartPolicy{}, append([]string{"http://example.com/panic"}, urls...))
+}
@@ -0,0 +1,249 @@
+/*
+	trazas (spans) para ver por dónde pasa cada url en el pipeline y cuánto tiempo estuvo en cada lugar.
+
+	por cada url que genera el producer se crea un span raíz y, por cada filtro que lo procesa, dos spans hijos:
+		- <filtro>.queue  el tiempo desde que la etapa anterior intentó empujarlo al canal hasta que este filtro lo sacó
+		- <filtro>        el tiempo que el filtro estuvo procesándolo (con el índice de la gorutina como atributo)
+
+	comparando ambos se ve cuánto del tiempo total es espera en los canales (el overhead de la táctica pipeline)
+	y cuánto es trabajo real.
+
+	las trazas se exportan al final de la ejecución en el formato JSON de OTLP (el mismo que usa OpenTelemetry)
+	para poder cargarlas en cualquier herramienta que lo entienda (Jaeger, otel-collector, etc.)
+*/
+
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Tracer junta los spans terminados hasta que se exportan.
+// un Tracer nil es válido y no registra nada, así el pipeline no tiene que preguntar si las trazas están activas
+type Tracer struct {
+	service string
+
+	mu    sync.Mutex
+	spans []*Span
+}
+
+// Span es una operación con comienzo y fin dentro de una traza.
+// un Span nil es válido y todos sus métodos no hacen nada
+type Span struct {
+	tracer   *Tracer
+	traceID  [16]byte
+	spanID   [8]byte
+	parentID [8]byte
+	name     string
+	start    time.Time
+	end      time.Time
+	attrs    []attribute
+	err      error
+}
+
+type attribute struct {
+	key   string
+	value any
+}
+
+func NewTracer(service string) *Tracer {
+	return &Tracer{service: service}
+}
+
+// Start crea un span que comienza ahora. si parent es nil el span es raíz de una nueva traza
+func (t *Tracer) Start(parent *Span, name string, attrs ...any) *Span {
+	return t.StartAt(parent, name, time.Now(), attrs...)
+}
+
+// StartAt es igual a Start pero con un comienzo explícito, se usa para los spans de espera en los canales
+// cuyo comienzo es el momento en que la etapa anterior encoló el item
+// los atributos se pasan como pares clave, valor
+func (t *Tracer) StartAt(parent *Span, name string, start time.Time, attrs ...any) *Span {
+	if t == nil {
+		return nil
+	}
+
+	s := &Span{tracer: t, name: name, start: start}
+	if parent != nil {
+		s.traceID = parent.traceID
+		s.parentID = parent.spanID
+	} else {
+		rand.Read(s.traceID[:])
+	}
+	rand.Read(s.spanID[:])
+	s.SetAttributes(attrs...)
+
+	return s
+}
+
+// SetAttributes agrega atributos al span como pares clave, valor
+func (s *Span) SetAttributes(attrs ...any) {
+	if s == nil {
+		return
+	}
+	for i := 0; i+1 < len(attrs); i += 2 {
+		key, ok := attrs[i].(string)
+		if !ok {
+			continue
+		}
+		s.attrs = append(s.attrs, attribute{key: key, value: attrs[i+1]})
+	}
+}
+
+// RecordError marca el span como fallido
+func (s *Span) RecordError(err error) {
+	if s == nil {
+		return
+	}
+	s.err = err
+}
+
+// End termina el span ahora y lo deja listo para exportar
+func (s *Span) End() {
+	s.EndAt(time.Now())
+}
+
+func (s *Span) EndAt(end time.Time) {
+	if s == nil {
+		return
+	}
+	s.end = end
+
+	s.tracer.mu.Lock()
+	s.tracer.spans = append(s.tracer.spans, s)
+	s.tracer.mu.Unlock()
+}
+
+// las estructuras que siguen son el subconjunto del JSON de OTLP (ExportTraceServiceRequest) que usamos
+// ver https://github.com/open-telemetry/opentelemetry-proto/blob/main/opentelemetry/proto/trace/v1/trace.proto
+type otlpExport struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+}
+
+const (
+	otlpSpanKindInternal = 1
+	otlpStatusOk         = 1
+	otlpStatusError      = 2
+)
+
+// Export escribe los spans terminados en formato JSON de OTLP
+func (t *Tracer) Export(w io.Writer) error {
+	if t == nil {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	spans := make([]otlpSpan, 0, len(t.spans))
+	for _, s := range t.spans {
+		spans = append(spans, s.toOTLP())
+	}
+
+	export := otlpExport{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: []otlpKeyValue{toOTLPKeyValue("service.name", t.service)}},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: "website-pipeline"}, Spans: spans}},
+	}}}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(export)
+}
+
+func (s *Span) toOTLP() otlpSpan {
+	span := otlpSpan{
+		TraceID:           hex.EncodeToString(s.traceID[:]),
+		SpanID:            hex.EncodeToString(s.spanID[:]),
+		Name:              s.name,
+		Kind:              otlpSpanKindInternal,
+		StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+		Status:            otlpStatus{Code: otlpStatusOk},
+	}
+	if s.parentID != [8]byte{} {
+		span.ParentSpanID = hex.EncodeToString(s.parentID[:])
+	}
+	for _, a := range s.attrs {
+		span.Attributes = append(span.Attributes, toOTLPKeyValue(a.key, a.value))
+	}
+	if s.err != nil {
+		span.Status = otlpStatus{Code: otlpStatusError, Message: s.err.Error()}
+	}
+	return span
+}
+
+func toOTLPKeyValue(key string, value any) otlpKeyValue {
+	kv := otlpKeyValue{Key: key}
+	switch v := value.(type) {
+	case int:
+		str := strconv.Itoa(v)
+		kv.Value.IntValue = &str
+	case int64:
+		str := strconv.FormatInt(v, 10)
+		kv.Value.IntValue = &str
+	case float64:
+		kv.Value.DoubleValue = &v
+	case bool:
+		kv.Value.BoolValue = &v
+	case string:
+		kv.Value.StringValue = &v
+	default:
+		str := fmt.Sprint(v)
+		kv.Value.StringValue = &str
+	}
+	return kv
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+// verifica que los spans de cada etapa queden como hijos del span raíz del url y que
+// el export respete el formato JSON de OTLP
+func TestTracerExportsStageSpansAsChildren(t *testing.T) {
+	tr := NewTracer("test")
+	saved := tracer
+	tracer = tr
+	defer func() { tracer = saved }()
+
+	root := tr.Start(nil, "website", "url", "http://example.com")
+	it := item{value: "http://example.com", span: root, enqueued: time.Now().Add(-time.Millisecond)}
+
+	span := startStage(it, "checkWebsite", 3)
+	span.RecordError(errors.New("no existe"))
+	span.End()
+	root.End()
+
+	var buf bytes.Buffer
+	if err := tr.Export(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	var export otlpExport
+	if err := json.Unmarshal(buf.Bytes(), &export); err != nil {
+		t.Fatalf("el export no es JSON válido: %v", err)
+	}
+	spans := export.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 3 {
+		t.Fatalf("se esperaban 3 spans (queue, etapa y raíz) y hay %d", len(spans))
+	}
+
+	byName := map[string]otlpSpan{}
+	for _, s := range spans {
+		byName[s.Name] = s
+	}
+	rootSpan := byName["website"]
+	if rootSpan.ParentSpanID != "" {
+		t.Errorf("el span raíz no debe tener padre, tiene %s", rootSpan.ParentSpanID)
+	}
+	for _, name := range []string{"checkWebsite.queue", "checkWebsite"} {
+		s, ok := byName[name]
+		if !ok {
+			t.Fatalf("falta el span %s", name)
+		}
+		if s.TraceID != rootSpan.TraceID || s.ParentSpanID != rootSpan.SpanID {
+			t.Errorf("el span %s no es hijo del span raíz", name)
+		}
+	}
+	if got := byName["checkWebsite"].Status.Code; got != otlpStatusError {
+		t.Errorf("el span con error debe tener status %d, tiene %d", otlpStatusError, got)
+	}
+	if got := byName["checkWebsite"].Attributes[1]; got.Key != "worker" || got.Value.IntValue == nil || *got.Value.IntValue != "3" {
+		t.Errorf("el atributo worker no se exportó como entero: %+v", got)
+	}
+}
+
+// un tracer nil no debe registrar nada ni fallar, es el caso sin el flag -trace
+func TestNilTracerIsNoop(t *testing.T) {
+	var tr *Tracer
+	span := tr.Start(nil, "website")
+	span.SetAttributes("url", "http://example.com")
+	span.End()
+
+	if err := tr.Export(&bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+}
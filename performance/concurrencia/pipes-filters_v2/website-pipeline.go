@@ -9,10 +9,18 @@
 
 	en el ejemplo se utilizan el fan out y el fan in para mostrar ambos, pero no siempre es necesario utilizarlos. 
 	el beneficio del fan out es que se procesa un feed en varias gorutinas concurrentes. se lanzan tantas gorutinas de cada paso como 
-	CPUs disponibles tengamos.
+	CPUs disponibles tengamos (o las que se indiquen con -workers).
 
 	también se agregó el uso de Contexto para cancelar (la opción de cancel esta comentada en el sink)
 
+	por los canales no viajan strings sino items (ver type item) que además del valor llevan el span de la traza
+	del url, así con go run . -trace=trazas.json se puede ver cuánto esperó cada url en cada canal y cuánto
+	demoró cada filtro en procesarlo (ver tracing.go)
+
+	todo se registra con logs estructurados (log/slog) con los mismos campos que el resto de los ejemplos
+	(run_id, url, stage, worker_id, attempt, duration). los mensajes que muestran cómo se arma el pipeline
+	son de nivel debug, se ven con -log-level=debug. con -log-format=json los registros salen en JSON
+
 	ejemplo basado en
 		https://go.dev/blog/pipelines , https://medium.com/amboss/applying-modern-go-concurrency-patterns-to-data-pipelines-b3b5327908d4
 		y el libro Concurrency in Go por Katherine Cox-Buday
@@ -34,115 +42,187 @@ package main
 import (
 	"fmt"
 	"errors"
-	"net/http"
+	"flag"
+	"os"
+	"os/signal"
 	"strings"
 	"context"
-	"log"
+	"log/slog"
 	"runtime"
 	"sync"
+	"time"
+
+	"arqsoft/tacticas-arq-go/performance/concurrencia/checker"
 )
 
+// logger de la ejecución, main lo configura con los flags -log-format y -log-level
+var logger = slog.Default()
+
+// prober de cada url, main le agrega las tácticas de los flags (ver checker)
+var prober checker.Prober = checker.NewHeadProber(nil)
+
+// tracer de la ejecución, queda en nil (no traza nada) si no se pasa el flag -trace
+var tracer *Tracer
+
+// cantidad de workers de cada etapa del fan out, por defecto uno por CPU. main la configura con -workers
+var workers = runtime.NumCPU()
+
+// item es lo que viaja por los canales del pipeline: el valor que procesa cada filtro, el resultado
+// de la verificación (para el resumen del sink), el span raíz de la traza del url y el momento en que
+// la etapa anterior lo empujó al canal
+type item struct {
+	url      string
+	value    string
+	result   checker.Result
+	span     *Span
+	enqueued time.Time
+}
+
+// next arma el item que la etapa empuja al siguiente canal con el resultado de procesarlo
+func (it item) next(value string) item {
+	return item{url: it.url, value: value, result: it.result, span: it.span, enqueued: time.Now()}
+}
+
+// stageError es lo que viaja por los canales de error, lleva los datos del url y de la etapa
+// donde falló para que el sink los pueda registrar
+type stageError struct {
+	stage   string
+	worker  int
+	message string
+	result  checker.Result
+}
+
+func (e *stageError) Error() string {
+	return fmt.Sprintf("%s: %v", e.message, e.result.Err)
+}
+
+func (e *stageError) Unwrap() error {
+	return e.result.Err
+}
+
+// startStage registra cuánto esperó el item en el canal antes de que el filtro lo sacara
+// y comienza el span del procesamiento en el filtro, que debe terminar quien lo llama
+func startStage(it item, stage string, worker int) *Span {
+	tracer.StartAt(it.span, stage+".queue", it.enqueued, "stage", stage, "worker", worker).End()
+	return tracer.Start(it.span, stage, "stage", stage, "worker", worker)
+}
+
 // función extraída de checkWebsite para hacerla mas legible a checkWebsite
-func callHead(url string) (string, error) {
+// además del mensaje devuelve el resultado del prober con el error si lo hubo. el url no va en el mensaje
+// porque puede tener usuario y contraseña, se registra con los campos del resultado (ver checker.RedactURL)
+func callHead(ctx context.Context, url string) (string, checker.Result) {
 	var ret string
 
 	// el que sigue es el mismo código que en el ejemplo secuencial
-	response, err := http.Head(url)
-	if err != nil {
-		 ret = fmt.Sprintf("El url %s no existe \n", url)
+	res := prober.Probe(ctx, url)
+	if res.Skipped() {
+		// no se verificó, por ejemplo porque el circuito del host está abierto
+		ret = fmt.Sprintf("El url no se verificó (%s) \n", res.Err)
+	} else if res.Err != nil {
+		// el mensaje dice por qué falló: no existe, conexión rechazada, timeout, etc.
+		ret = fmt.Sprintf("El url falló: %s \n", res.Kind().Message())
 	} else {
-		// ok Head sin error, si no es ok retorna url:false si es ok url:true
-		if response.StatusCode != http.StatusOK {
-			ret = fmt.Sprintf("El url %s NO responde OK código %d \n", url, response.StatusCode)
+		// ok Head sin error, si no cumple los criterios de éxito retorna url:false, si los cumple url:true
+		if !res.OK() {
+			ret = fmt.Sprintf("El url NO responde OK código %d %s \n", res.StatusCode, strings.Join(res.Unmet, "; "))
 		} else {
-			ret = fmt.Sprintf("El url %s responde OK \n", url)
+			ret = "El url responde OK \n"
 		}
 	}
-	return ret, err
+	return ret, res
 }
 
-// esta función es la que llama a la goroutina que checkean los urls  
+// esta función es la gorutina que checkea los urls  
 // usa el select que en caso de que haya algo en el canal de in lo procesa (callhead) y lo
 // empuja al canal out
 // el select chequea el done y en caso que venga algo en ese canal termina prolijamente la ejecución de 
 // la gorutina
 // si un url no existe lo pasa al canal de errores para que lo maneje el sink
-func checkWebsite(ctx context.Context, in <-chan string) (<-chan string, <-chan error, error) {
-
-	out := make(chan string)
-	errorChannel := make(chan error)
-	
-	go func() {
-		defer close(out)
-		defer close(errorChannel)
-
-		for url := range in {
-			select {
-			case <- ctx.Done():
-				return	
-			default:
-				url, err := callHead(url)
-				if err != nil {
-					errorChannel <- errors.New(url)
-				} else {
-					out <- url
+// worker es el índice de la gorutina en el fan out, se usa para las trazas
+// la gorutina la lanza el supervisor (ver supervisor.go), que es también quien cierra los canales
+func checkWebsite(ctx context.Context, in <-chan item, out chan<- item, errorChannel chan<- error, worker int) {
+	for it := range in {
+		select {
+		case <- ctx.Done():
+			return	
+		default:
+			span := startStage(it, "checkWebsite", worker)
+			url, res := callHead(ctx, it.value)
+			if res.Err != nil {
+				// el url no sigue por el pipeline, su traza termina acá
+				span.RecordError(res.Err)
+				span.End()
+				it.span.RecordError(res.Err)
+				it.span.End()
+				select {
+				case errorChannel <- &stageError{stage: "checkWebsite", worker: worker, message: strings.TrimSpace(url), result: res}:
+				case <-ctx.Done():
+					return
+				}
+			} else {
+				span.End()
+				logger.Debug("url verificado", append(res.Attrs(), checker.KeyStage, "checkWebsite", checker.KeyWorkerID, worker)...)
+				checked := it.next(url)
+				checked.result = res
+				select {
+				case out <- checked:
+				case <-ctx.Done():
+					return
 				}
-			
 			}
+		
 		}
-	}()
-	
-	return out, errorChannel, nil
-
+	}
 }
 
 //esta gorutina convierte un string a mayúscula
-func convertResultaToUpperCase(ctx context.Context, in <-chan string) (<-chan string, <-chan error, error) {
-
-	out := make(chan string)
-	errorChannel := make(chan error)
-
-	go func() {
-		defer close(out)
-		defer close(errorChannel)
-
-		for result := range in {
+func convertResultaToUpperCase(ctx context.Context, in <-chan item, out chan<- item, errorChannel chan<- error, worker int) {
+	for result := range in {
+		select {
+		case <- ctx.Done():
+			return
+		default:
+			span := startStage(result, "convertResultaToUpperCase", worker)
+			upper := strings.ToUpper(result.value)
+			span.End()
 			select {
-			case <- ctx.Done():
+			case out <- result.next(upper):
+			case <-ctx.Done():
 				return
-			default:
-				out <- strings.ToUpper(result)
 			}
 		}
-	}()
-	
-	return out, errorChannel, nil
+	}
+}
 
+// urls que verifica el main
+var websites = []string{
+	"http://ort.edu.uy",
+	"http://google.com",
+	"http://github.com",
+	"http://arqsoft.com",
+	"http://netflix.com",
+	"http://instagram.com",
+	"http://ingsoft.gaston.com",
+	"http://gitlab.com",
+	"http://gaston.arq.com",
 }
 
 // funciona generadora es la fuente de datos que alimenta el stream que va a pasar por el pipeline. 
-func producer(ctx context.Context) (<-chan string, error) {
-	out := make(chan string)
+func producer(ctx context.Context, urls []string) (<-chan item, error) {
+	out := make(chan item)
 	
-	var websites = []string{
-		"http://ort.edu.uy",
-		"http://google.com",
-		"http://github.com",
-		"http://arqsoft.com",
-		"http://netflix.com",
-		"http://instagram.com",
-		"http://ingsoft.gaston.com",
-		"http://gitlab.com",
-		"http://gaston.arq.com",
-	}
 	go func ()  {
 		defer close(out)
-		for _, ws := range websites {
+		for _, ws := range urls {
+			// cada url es una traza nueva, el span raíz termina cuando el url sale del pipeline
+			span := tracer.Start(nil, "website", "url", checker.RedactURL(ws))
+			// el envío también mira ctx: si se cancela el pipeline nadie más lee de out y sin el select
+			// el producer quedaría bloqueado para siempre
 			select {
+			case out <- item{url: ws, value: ws, span: span, enqueued: time.Now()}:
 			case <-ctx.Done():
+				span.End()
 				return
-			default:
-				out <- ws
 			}
 		}
 	}()
@@ -152,36 +232,92 @@ func producer(ctx context.Context) (<-chan string, error) {
 
 // función sink que despliega los url procesados. en caso de que venga algo en el canal de errores cancela todos los pipelines
 // (ver cancel comentado para hacer el log y no terminar todo el pipeline)
-func sink(ctx context.Context, cancel context.CancelFunc,values <-chan string, errors <-chan error) {
-	for {
+// cada url que llega, con o sin error, se suma al resumen
+// termina cuando se cerraron los dos canales: si terminara al cerrarse values se perderían los errores que
+// todavía no leyó, y las gorutinas que los envían quedarían bloqueadas hasta que se cancele el pipeline
+func sink(ctx context.Context, cancel context.CancelFunc,values <-chan item, errors <-chan error, summary *checker.Summary) {
+	for values != nil || errors != nil {
 		select {
 		case <-ctx.Done():
-			log.Print(ctx.Err().Error())
+			logger.Warn("pipeline cancelado", checker.KeyStage, "sink", checker.KeyError, ctx.Err())
+			// lo que queda se descarta sin registrarlo, pero se espera a que las etapas (que también ven el ctx
+			// cancelado) cierren sus canales: así WebsiteStatusChecker no retorna con gorutinas del pipeline vivas
+			if values != nil {
+				for range values {
+				}
+			}
+			if errors != nil {
+				for range errors {
+				}
+			}
 			return
 			
 		case err, ok := <-errors:
-		if ok {
-       //cancel()
-				log.Print(err.Error())
+			if !ok {
+				// un canal nil nunca está listo, el select sigue solo con values
+				errors = nil
+				continue
+			}
+			//cancel()
+			logError(err)
+			summary.Add(errorResult(err))
+			// si el supervisor agotó los reinicios la etapa quedó sin el worker: se cancela todo el pipeline
+			if panicErr := escalated(err); panicErr != nil {
+				logger.Error("se agotaron los reinicios, se cancela el pipeline", checker.KeyStage, panicErr.stage)
+				cancel()
 			}
 	
 		case val, ok := <-values:
-			if ok {
-				log.Printf("sink: %s", val)
-			} else {
-				log.Print("done")
-				return
+			if !ok {
+				values = nil
+				continue
 			}
+			span := startStage(val, "sink", 0)
+			logger.Info(strings.TrimSpace(val.value), append(val.result.Attrs(), checker.KeyStage, "sink")...)
+			summary.Add(val.result)
+			span.End()
+			val.span.End()
 		}
 	}
+	logger.Debug("done", checker.KeyStage, "sink")
+}
+
+// registra un error que llegó al sink, con los datos de la etapa donde ocurrió si los tiene
+func logError(err error) {
+	var stageErr *stageError
+	if errors.As(err, &stageErr) {
+		logger.Warn(stageErr.message, append(stageErr.result.Attrs(), checker.KeyStage, stageErr.stage, checker.KeyWorkerID, stageErr.worker)...)
+		return
+	}
+	var panicErr *panicError
+	if errors.As(err, &panicErr) {
+		logger.Error(panicErr.Error(), checker.KeyStage, panicErr.stage, checker.KeyWorkerID, panicErr.worker, "stack", string(panicErr.stack))
+		return
+	}
+	logger.Warn("error en el pipeline", checker.KeyError, err)
+}
+
+// errorResult devuelve el resultado de la verificación que falló, o uno armado con el error si no lo tiene
+func errorResult(err error) checker.Result {
+	var stageErr *stageError
+	if errors.As(err, &stageErr) {
+		return stageErr.result
+	}
+	return checker.Result{Err: err}
+}
+
+// fatal registra el error y termina la ejecución, como log.Fatal pero con el logger estructurado
+func fatal(msg string, err error) {
+	logger.Error(msg, checker.KeyError, err)
+	os.Exit(1)
 }
 
 // mergea canales de entrada a uno de salida
-func mergeStringChans(ctx context.Context, cs ...<-chan string) <-chan string {
+func mergeItemChans(ctx context.Context, cs ...<-chan item) <-chan item {
 	var wg sync.WaitGroup
-	out := make(chan string)
+	out := make(chan item)
 
-	output := func(c <-chan string) {
+	output := func(c <-chan item) {
 		defer wg.Done()
 		for n := range c {
 			select {
@@ -245,9 +381,20 @@ func mergeErrorChans(ctx context.Context, cs ...<-chan error) <-chan error {
 	es donde se juntan todas las ramas o donde termina el pipeline
 
 */
-func WebsiteStatusChecker()	{
+func WebsiteStatusChecker(ctx context.Context, urls []string)	{
+
+	// se normaliza la lista, ver checker.PrepareURLs
+	prepared := checker.PrepareURLs(urls)
+	prepared.Log(logger)
+	urls = prepared.URLs
+
+	// al final se registra el resumen, ver checker.Summary
+	var summary checker.Summary
+	summary.Add(prepared.Invalid...)
+	defer summary.Log(logger)
 
-	ctx, cancel := context.WithCancel(context.Background())
+	// se puede cancelar desde afuera (ctrl-c) o desde el sink
+	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
 
@@ -255,51 +402,64 @@ func WebsiteStatusChecker()	{
 	done := make(chan struct{})
 	defer close(done)
 
-	fmt.Printf("---> llamar producer *****\n")
+	logger.Debug("llamar producer", checker.KeyStage, "producer")
 	// empuja por el canal in los urls
-	in, err := producer(ctx)
+	in, err := producer(ctx, urls)
 	if err != nil {
-		log.Fatal(err)
+		fatal("no se pudo crear el producer", err)
 	}
 
 
+	// cada worker corre bajo el supervisor, que recupera sus panics y lo reinicia según -max-restarts
+	sup := newSupervisor(restarts)
+
 	// fan out stage1
-	stage1Channels := []<-chan string{}
+	stage1Channels := []<-chan item{}
 	errors := []<-chan error{}
-	fmt.Printf("---> for stage1 *****\n")
-	for i := 0; i < runtime.NumCPU(); i++ {
-		fmt.Printf("---> for stage1 (%d) *****\n",i)
-		websiteCheckChannel, websiteCheckErrors, err := checkWebsite(ctx, in)
+	logger.Debug("fan out stage1", checker.KeyStage, "checkWebsite", "workers", workers)
+	for i := 0; i < workers; i++ {
+		logger.Debug("se lanza la gorutina", checker.KeyStage, "checkWebsite", checker.KeyWorkerID, i)
+		// con -faults se agrega delante de la etapa el filtro que inyecta las fallas, ver faults.go
+		websiteCheckChannel, websiteCheckErrors, err := withFaults(sup, "checkWebsite", checkWebsite)(ctx, in, i)
 		if err != nil {
-			log.Fatal(err)
+			fatal("no se pudo crear la etapa checkWebsite", err)
 		}
 		stage1Channels = append(stage1Channels, websiteCheckChannel)
 		errors = append(errors, websiteCheckErrors)
 	}
 
 	// fan in - stage1
-	stage1Merged := mergeStringChans(ctx, stage1Channels...)
+	stage1Merged := mergeItemChans(ctx, stage1Channels...)
 
 	// fan out stage2
-	fmt.Printf("---> for stage2 *****\n")
-	stage2Channels := []<-chan string{}
+	logger.Debug("fan out stage2", checker.KeyStage, "convertResultaToUpperCase", "workers", workers)
+	stage2Channels := []<-chan item{}
 
-	for i := 0; i < runtime.NumCPU(); i++ {
-		fmt.Printf("---> for stage2 (%d) *****\n",i)
-		toUpperCaseChannel, toUpperErrors, err := convertResultaToUpperCase(ctx, stage1Merged)
+	for i := 0; i < workers; i++ {
+		logger.Debug("se lanza la gorutina", checker.KeyStage, "convertResultaToUpperCase", checker.KeyWorkerID, i)
+		toUpperCaseChannel, toUpperErrors, err := withFaults(sup, "convertResultaToUpperCase", convertResultaToUpperCase)(ctx, stage1Merged, i)
 		if err != nil {
-			log.Fatal(err)
+			fatal("no se pudo crear la etapa convertResultaToUpperCase", err)
 		}
 		stage2Channels = append(stage2Channels, toUpperCaseChannel)
 		errors = append(errors, toUpperErrors)
 	}
 
-	stage2Merged := mergeStringChans(ctx, stage2Channels...)
+	stage2Merged := mergeItemChans(ctx, stage2Channels...)
+	stage2Merged, sinkFaults := sinkWithFaults(ctx, sup, stage2Merged)
+	if sinkFaults != nil {
+		errors = append(errors, sinkFaults)
+	}
 
 	// fan in - stage2
 	errorsMerged := mergeErrorChans(ctx, errors...)
-	fmt.Printf("---> sink *****\n")
-	sink(ctx, cancel, stage2Merged, errorsMerged)
+	logger.Debug("llamar sink", checker.KeyStage, "sink")
+	sink(ctx, cancel, stage2Merged, errorsMerged, &summary)
+
+	// si una etapa se quedó sin workers nadie más lee del producer: se cancela y se espera a que cierre su canal
+	cancel()
+	for range in {
+	}
 
 }
 	
@@ -307,12 +467,111 @@ func WebsiteStatusChecker()	{
 
 // solo llama a la función de verificar sitios con un slice de urls, se separó para poder invocar WebsiteStatusChecker(); 
 // desde los tests
+// con -trace=archivo.json exporta las trazas de la ejecución en formato OTLP JSON (-trace=- para la consola)
 func main() {
-	// declara un array de urls para chequear
+	traceOut := flag.String("trace", "", "archivo donde exportar las trazas en formato OTLP JSON, - para stdout")
+	logConfig := checker.RegisterLogFlags(flag.CommandLine)
+	probeConfig := checker.RegisterProbeFlags(flag.CommandLine)
+	// urls de -inventory y -sitemap, ver checker.Inventory
+	inventory := checker.RegisterInventoryFlag(flag.CommandLine, probeConfig)
+	// profiling y modo daemon, ver checker/profile.go
+	profileConfig := checker.RegisterProfileFlags(flag.CommandLine)
+	// con -crawl además de verificar los urls se siguen los links de sus páginas, ver crawler.go
+	crawlMode := flag.Bool("crawl", false, "seguir los links de las páginas y reportar los links rotos")
+	crawlDepth := flag.Int("crawl-depth", 2, "saltos máximos desde los urls iniciales en el modo crawler")
+	crawlMaxPages := flag.Int("crawl-max-pages", 100, "cantidad máxima de urls que se verifican en el modo crawler")
+	// con -faults=archivo.json se inyectan fallas en las etapas, ver faults.go
+	faultsPath := flag.String("faults", "", "archivo JSON con las fallas a inyectar en cada etapa del pipeline")
+	faultSeed := flag.Int64("fault-seed", 0, "seed de las fallas inyectadas (0 para la del archivo)")
+	// los workers que hacen panic se reinician, ver supervisor.go
+	flag.IntVar(&restarts.MaxRestarts, "max-restarts", 0, "reinicios permitidos de los workers que hacen panic, con 0 el primer panic cancela el pipeline")
+	flag.DurationVar(&restarts.Window, "restart-window", time.Minute, "ventana en la que se cuentan los reinicios, 0 para toda la ejecución")
+	flag.IntVar(&workers, "workers", workers, "workers de cada etapa del pipeline (y del crawler)")
+	flag.Parse()
+	if workers < 1 {
+		fmt.Fprintln(os.Stderr, "-workers debe ser al menos 1")
+		os.Exit(2)
+	}
 
-	fmt.Printf("*****comienzo *****\n")
+	var err error
+	if logger, err = logConfig.Logger(os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if prober, err = probeConfig.Prober(logger); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if *faultsPath != "" {
+		if faults, err = loadFaults(*faultsPath, *faultSeed); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+	}
+	// el crawler descarga las páginas con la misma configuración y las mismas tácticas que el prober
+	pages = probeConfig.Wrap(pageProber(probeConfig.Client(), probeConfig.Requests()), "page")
 
-	WebsiteStatusChecker();
+	if *traceOut != "" {
+		tracer = NewTracer("pipes-filters_v2")
+	}
+
+	urls, err := inventory.URLs(websites)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	profiler, err := profileConfig.Start(logger)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	logger.Info("*****comienzo *****")
+	start := time.Now()
+
+	// con ctrl-c se cancelan las verificaciones que faltan
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	profileConfig.Loop(ctx, func() {
+		if *crawlMode {
+			Crawl(ctx, urls, crawlConfig{maxDepth: *crawlDepth, maxPages: *crawlMaxPages, workers: workers})
+		} else {
+			WebsiteStatusChecker(ctx, urls)
+		}
+	})
 
-	fmt.Printf("***** FIN *****")
+	logger.Info("***** FIN *****", checker.KeyDuration, time.Since(start))
+
+	if err := probeConfig.Close(); err != nil {
+		logger.Warn("no se pudo guardar la cache", checker.KeyError, err)
+	}
+	if err := profiler.Stop(); err != nil {
+		logger.Warn("no se pudieron guardar los perfiles", checker.KeyError, err)
+	}
+
+	if err := exportTraces(*traceOut); err != nil {
+		fatal("no se pudieron exportar las trazas", err)
+	}
+}
+
+// exportTraces escribe las trazas en el archivo (o stdout si es -) al terminar la ejecución
+func exportTraces(path string) error {
+	switch path {
+	case "":
+		return nil
+	case "-":
+		return tracer.Export(os.Stdout)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := tracer.Export(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
 }
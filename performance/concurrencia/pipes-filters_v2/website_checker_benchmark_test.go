@@ -4,19 +4,81 @@
 
 package main
 
-import("testing")
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"arqsoft/tacticas-arq-go/performance/concurrencia/checker"
+	"arqsoft/tacticas-arq-go/performance/concurrencia/checker/checkertest"
+)
 
 
 
 	  // benchmark de llamadas secuenciales
 func BenchmarkWebsiteChecker(b *testing.B) {
 
-	// declara un slice de urls para chequear
 	   // Demora tenga paciencia!
 	   // hace el benchmark hasta que N que es un valor que el runtime determina para que sea significativo
 	   // Demora tenga paciencia!
 	   //
-	for i:=1 ; i < b.N; i++ {
-		WebsiteStatusChecker()
+	for i := 0; i < b.N; i++ {
+		WebsiteStatusChecker(context.Background(), websites)
+	}
+}
+
+// benchmark de la táctica de hedging contra un servidor local en el que 1 de cada 20 pedidos demora 300ms.
+// compara el prober sin tácticas con el que lanza un segundo HEAD pasado el p90 de las latencias observadas,
+// ver los percentiles p50-ns y p99-ns del tiempo de cada ejecución
+// go test -bench TailLatency
+func BenchmarkTailLatency(b *testing.B) {
+	server := checkertest.NewServer(checkertest.TailLatency(2*time.Millisecond, 300*time.Millisecond, 20))
+	defer server.Close()
+	urls := checkertest.URLs(server, 20)
+
+	savedLogger, savedProber := logger, prober
+	defer func() { logger, prober = savedLogger, savedProber }()
+	logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	strategies := []struct {
+		name   string
+		prober checker.Prober
+	}{
+		{"plain", checker.NewHeadProber(server.Client())},
+		{"hedged", checker.Chain(checker.NewHeadProber(server.Client()),
+			checker.Hedge(checker.HedgeConfig{Percentile: 90, Delay: 20 * time.Millisecond}))},
+	}
+
+	for _, s := range strategies {
+		b.Run(s.name, func(b *testing.B) {
+			prober = s.prober
+			durations := make([]time.Duration, 0, b.N)
+			for i := 0; i < b.N; i++ {
+				start := time.Now()
+				WebsiteStatusChecker(context.Background(), urls)
+				durations = append(durations, time.Since(start))
+			}
+			checkertest.ReportPercentiles(b, durations)
+		})
+	}
+}
+
+// benchmark de escalabilidad con un prober falso, sin salir a la red: para cada cantidad de workers del fan out
+// verifica 10, 100, 1000 y 10000 urls con cada perfil de latencia de checkertest y reporta allocations, urls/s
+// y max-inflight. para guardar los resultados en el formato de benchstat
+// go test -bench Scalability -count 6 | tee resultados.txt
+func BenchmarkScalability(b *testing.B) {
+	savedLogger, savedProber, savedWorkers := logger, prober, workers
+	defer func() { logger, prober, workers = savedLogger, savedProber, savedWorkers }()
+	logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	for _, w := range []int{1, 2, 4, 8, 16} {
+		b.Run(fmt.Sprintf("workers=%d", w), func(b *testing.B) {
+			workers = w
+			checkertest.BenchmarkWorkloads(b, func(p checker.Prober) { prober = p }, WebsiteStatusChecker)
+		})
 	}
-}
\ No newline at end of file
+}
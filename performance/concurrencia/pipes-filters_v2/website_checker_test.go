@@ -0,0 +1,57 @@
+// pruebas de correctitud con un prober falso, se pueden ejecutar con el detector de carreras:
+// go test -race ./...
+
+package main
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"arqsoft/tacticas-arq-go/performance/concurrencia/checker"
+	"arqsoft/tacticas-arq-go/performance/concurrencia/checker/checkertest"
+)
+
+// strategy le indica a checkertest cómo reemplazar el logger y el prober de este ejemplo y qué función probar
+var strategy = checkertest.Strategy{
+	Use: func(l *slog.Logger, p checker.Prober) func() {
+		savedLogger, savedProber := logger, prober
+		logger, prober = l, p
+		return func() { logger, prober = savedLogger, savedProber }
+	},
+	Check: WebsiteStatusChecker,
+	// al cancelar el sink deja de leer enseguida, los urls que no llegaron no se registran
+	DropsOnCancel: true,
+}
+
+// las pruebas que comparten todas las estrategias, ver checkertest.RunStrategyTests
+func TestWebsiteStatusChecker(t *testing.T) {
+	checkertest.RunStrategyTests(t, strategy)
+}
+
+// el fan out de cada etapa verifica con tantas gorutinas como -workers, ni más ni menos
+func TestWebsiteStatusCheckerUsesWorkers(t *testing.T) {
+	saved := workers
+	t.Cleanup(func() { workers = saved })
+	workers = 4
+
+	urls, fake, _ := strategy.UseFakes(t, 20*time.Millisecond, 20)
+	WebsiteStatusChecker(context.Background(), urls)
+	if n := fake.MaxInFlight(); n != workers {
+		t.Errorf("hubo %d verificaciones a la vez, se esperaban %d (workers)", n, workers)
+	}
+}
+
+// al cancelar el sink registra la cancelación del pipeline
+func TestWebsiteStatusCheckerLogsCancel(t *testing.T) {
+	checkertest.CheckLeaks(t)
+	urls, _, logs := strategy.UseFakes(t, time.Second, 20)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	WebsiteStatusChecker(ctx, urls)
+	if logs.Find("pipeline cancelado") == nil {
+		t.Error("no se registró la cancelación")
+	}
+}
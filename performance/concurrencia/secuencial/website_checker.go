@@ -1,7 +1,10 @@
 /* Ejecución secuencial - este ejemplo es la base para mostrar el uso de concurrencia en los ejemplos de las demás carpetas.
 Muestra un slice con urls que se recorre para ver si el llamado a HEAD retorna ok o no.
-La función func CheckWebsites(urls []string) recorre el slice y a la función bloqueante CheckOneWebsite(url string,  workerId int)
-que despliega en consola un string con true si el sitio responde, false si no lo hace o no existe si el HEAD devuelve error
+La función func CheckWebsites(ctx context.Context, urls []string) recorre el slice y a la función bloqueante CheckOneWebsite(ctx context.Context, url string,  workerId int)
+que registra en el log (log/slog) true si el sitio responde, false si no lo hace o por qué falló si el HEAD devuelve error
+(no existe, conexión rechazada, error de TLS, timeout, etc.). al final se registra un resumen con los errores por categoría
+
+los logs son estructurados, con los flags -log-format=json y -log-level=debug se cambia el formato y el nivel
 
 puede ejecutarlo con:
 go run . para ejecutar el main o
@@ -15,45 +18,94 @@ a la cantidad de segundos que duró la ejecución (con go test -bench)
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
-	"net/http"
+	"log/slog"
+	"os"
+	"os/signal"
+	"time"
+
+	"arqsoft/tacticas-arq-go/performance/concurrencia/checker"
 )
 
+// logger de la ejecución, main lo configura con los flags -log-format y -log-level
+var logger = slog.Default()
+
+// prober de cada url, main le agrega las tácticas de los flags (ver checker)
+var prober checker.Prober = checker.NewHeadProber(nil)
+
 // funcion que chequea si un sitio reponde
+// si se cancela ctx los urls que faltan se reportan como cancelados sin esperar su HEAD
+
+func CheckWebsites(ctx context.Context, urls []string) {
+	// se normaliza la lista, ver checker.PrepareURLs
+	prepared := checker.PrepareURLs(urls)
+	prepared.Log(logger)
+	urls = prepared.URLs
+
+	// al final se registra el resumen, ver checker.Summary
+	var summary checker.Summary
+	summary.Add(prepared.Invalid...)
+	defer summary.Log(logger)
 
-func CheckWebsites(urls []string) {
 	var workers int = 1
 	// recorre el slice de urls y llama a HEAD
 	for _, url := range urls {
 
-		CheckOneWebsite(url, workers)
+		summary.Add(CheckOneWebsite(ctx, url, workers))
 		workers++
 
 	}
 
 }
 
-// esta función hace HEAD de el URL e imprime si responde o no
-func CheckOneWebsite(url string, workerId int) {
+// esta función hace HEAD de el URL, registra en el log si responde o no y devuelve el resultado
+// si el HEAD da error se registra la categoría (no existe, conexión rechazada, timeout, etc.)
+func CheckOneWebsite(ctx context.Context, url string, workerId int) checker.Result {
 
-	response, err := http.Head(url)
-	if err != nil {
-		fmt.Printf("workerid: %d para url %s no existe \n", workerId, url)
+	urlLogger := logger.With(checker.KeyStage, "CheckOneWebsite", checker.KeyWorkerID, workerId)
+
+	res := prober.Probe(ctx, url)
+	if res.Skipped() {
+		// no se verificó, por ejemplo porque el circuito del host está abierto
+		urlLogger.Warn(res.Err.Error(), res.Attrs()...)
+	} else if res.Err != nil {
+		urlLogger.Warn(res.Kind().Message(), res.Attrs()...)
 
 	} else {
 
-		// ok Head sin error, si no es ok retorna url:false si es ok url:true
-		if response.StatusCode != http.StatusOK {
-			fmt.Printf("workerid: %d para url %s resulta False \n", workerId, url)
+		// ok Head sin error, si no cumple los criterios de éxito retorna url:false, si los cumple url:true
+		if !res.OK() {
+			urlLogger.Info("resulta False", res.Attrs()...)
 		} else {
-			fmt.Printf("workerid: %d para url %s resulta True \n", workerId, url)
+			urlLogger.Info("resulta True", res.Attrs()...)
 		}
 
 	}
+	return res
 }
 
 // solo llama a la función de verificar sitios con un slice de urls
 func main() {
+	logConfig := checker.RegisterLogFlags(flag.CommandLine)
+	probeConfig := checker.RegisterProbeFlags(flag.CommandLine)
+	// urls de -inventory y -sitemap, ver checker.Inventory
+	inventory := checker.RegisterInventoryFlag(flag.CommandLine, probeConfig)
+	// profiling y modo daemon, ver checker/profile.go
+	profileConfig := checker.RegisterProfileFlags(flag.CommandLine)
+	flag.Parse()
+
+	var err error
+	if logger, err = logConfig.Logger(os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if prober, err = probeConfig.Prober(logger); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
 	var websites = []string{
 		"http://ort.edu.uy",
 		"http://google.com",
@@ -64,10 +116,33 @@ func main() {
 		"http://ingsoft.gaston.com",
 	}
 
-	fmt.Printf("***** comienzo *****\n")
+	urls, err := inventory.URLs(websites)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	profiler, err := profileConfig.Start(logger)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	logger.Info("***** comienzo *****")
+	start := time.Now()
+
+	// con ctrl-c se cancelan las verificaciones que faltan
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	profileConfig.Loop(ctx, func() { CheckWebsites(ctx, urls) })
 
-	CheckWebsites(websites)
+	logger.Info("***** FIN *****", checker.KeyDuration, time.Since(start))
 
-	fmt.Printf("***** FIN *****")
+	if err := probeConfig.Close(); err != nil {
+		logger.Warn("no se pudo guardar la cache", checker.KeyError, err)
+	}
+	if err := profiler.Stop(); err != nil {
+		logger.Warn("no se pudieron guardar los perfiles", checker.KeyError, err)
+	}
 
 }
@@ -5,7 +5,13 @@
 package main
 
 import (
+	"context"
+	"io"
+	"log/slog"
 	"testing"
+
+	"arqsoft/tacticas-arq-go/performance/concurrencia/checker"
+	"arqsoft/tacticas-arq-go/performance/concurrencia/checker/checkertest"
 )
 
 // benchmark de llamadas secuenciales
@@ -26,8 +32,20 @@ func BenchmarkWebsiteChecker(b *testing.B) {
 	// Demora tenga paciencia!
 	//
 
-	for i := 1; i < b.N; i++ {
-		CheckWebsites(websites)
+	for i := 0; i < b.N; i++ {
+		CheckWebsites(context.Background(), websites)
 	}
 
 }
+
+// benchmark de escalabilidad con un prober falso, sin salir a la red: verifica 10, 100, 1000 y 10000 urls con
+// cada perfil de latencia de checkertest y reporta allocations, urls/s y max-inflight. para guardar los
+// resultados en el formato de benchstat
+// go test -bench Scalability -count 6 | tee resultados.txt
+func BenchmarkScalability(b *testing.B) {
+	savedLogger, savedProber := logger, prober
+	defer func() { logger, prober = savedLogger, savedProber }()
+	logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	checkertest.BenchmarkWorkloads(b, func(p checker.Prober) { prober = p }, CheckWebsites)
+}
@@ -0,0 +1,38 @@
+// pruebas de correctitud con un prober falso, se pueden ejecutar con el detector de carreras:
+// go test -race ./...
+
+package main
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"arqsoft/tacticas-arq-go/performance/concurrencia/checker"
+	"arqsoft/tacticas-arq-go/performance/concurrencia/checker/checkertest"
+)
+
+// strategy le indica a checkertest cómo reemplazar el logger y el prober de este ejemplo y qué función probar
+var strategy = checkertest.Strategy{
+	Use: func(l *slog.Logger, p checker.Prober) func() {
+		savedLogger, savedProber := logger, prober
+		logger, prober = l, p
+		return func() { logger, prober = savedLogger, savedProber }
+	},
+	Check: CheckWebsites,
+}
+
+// las pruebas que comparten todas las estrategias, ver checkertest.RunStrategyTests
+func TestCheckWebsites(t *testing.T) {
+	checkertest.RunStrategyTests(t, strategy)
+}
+
+// la estrategia secuencial verifica los urls de a uno
+func TestCheckWebsitesIsSequential(t *testing.T) {
+	urls, fake, _ := strategy.UseFakes(t, time.Millisecond, 10)
+	CheckWebsites(context.Background(), urls)
+	if n := fake.MaxInFlight(); n != 1 {
+		t.Errorf("hubo %d verificaciones a la vez, se esperaba una", n)
+	}
+}
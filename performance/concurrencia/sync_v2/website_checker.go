@@ -25,6 +25,9 @@
 	al ejecutar prestar atención al orden en que se despliega el workerId o el url y
 	a la cantidad de segundos que duró la ejecución (con go test -bench)
 
+	los resultados se registran con logs estructurados (log/slog), con los flags -log-format=json y
+	-log-level=debug se cambia el formato y el nivel
+
 
     el ejemplo se adapta de https://github.com/quii/learn-go-with-tests/tree/main/concurrency
 	y el libro Concurrency in Go por Katherine Cox-Buday
@@ -33,14 +36,38 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
-	"net/http"
+	"log/slog"
+	"os"
+	"os/signal"
 	"sync"
+	"time"
+
+	"arqsoft/tacticas-arq-go/performance/concurrencia/checker"
 )
 
+// logger de la ejecución, main lo configura con los flags -log-format y -log-level
+var logger = slog.Default()
+
+// prober de cada url, main le agrega las tácticas de los flags (ver checker)
+var prober checker.Prober = checker.NewHeadProber(nil)
+
 // esta función es la que llama a las goroutinas y las sincroniza
 // utilizando sync.WaitGroups
-func CheckWebsites(urls []string) {
+// si se cancela ctx los HEAD en curso se cortan y esos urls se reportan como cancelados
+func CheckWebsites(ctx context.Context, urls []string) {
+
+	// se normaliza la lista, ver checker.PrepareURLs
+	prepared := checker.PrepareURLs(urls)
+	prepared.Log(logger)
+	urls = prepared.URLs
+
+	// al final se registra el resumen, ver checker.Summary
+	var summary checker.Summary
+	summary.Add(prepared.Invalid...)
+	defer summary.Log(logger)
 
 	var workers int = 1
 
@@ -60,16 +87,23 @@ func CheckWebsites(urls []string) {
 
 			defer wg.Done() // cuanto termine defer avisar al WaitGroup
 
+			urlLogger := logger.With(checker.KeyStage, "CheckWebsites", checker.KeyWorkerID, wrkId)
+
 			// el que sigue es el mismo código que en el ejemplo secuencial
-			response, err := http.Head(u)
-			if err != nil {
-				fmt.Printf("workerid: %d para url %s no existe \n", wrkId, u)
+			res := prober.Probe(ctx, u)
+			// summary lo comparten las goroutines, Summary protege sus contadores con un sync.Mutex
+			summary.Add(res)
+			if res.Skipped() {
+				// no se verificó, por ejemplo porque el circuito del host está abierto
+				urlLogger.Warn(res.Err.Error(), res.Attrs()...)
+			} else if res.Err != nil {
+				urlLogger.Warn(res.Kind().Message(), res.Attrs()...)
 			} else {
-				// ok Head sin error, si no es ok retorna url:false si es ok url:true
-				if response.StatusCode != http.StatusOK {
-					fmt.Printf("workerid: %d para url %s resulta False \n", wrkId, u)
+				// ok Head sin error, si no cumple los criterios de éxito retorna url:false, si los cumple url:true
+				if !res.OK() {
+					urlLogger.Info("resulta False", res.Attrs()...)
 				} else {
-					fmt.Printf("workerid: %d para url %s resulta True \n", wrkId, u)
+					urlLogger.Info("resulta True", res.Attrs()...)
 				}
 			}
 		}(url, workers)
@@ -82,6 +116,24 @@ func CheckWebsites(urls []string) {
 
 // solo llama a la función de verificar sitios con un slice de urls
 func main() {
+	logConfig := checker.RegisterLogFlags(flag.CommandLine)
+	probeConfig := checker.RegisterProbeFlags(flag.CommandLine)
+	// urls de -inventory y -sitemap, ver checker.Inventory
+	inventory := checker.RegisterInventoryFlag(flag.CommandLine, probeConfig)
+	// profiling y modo daemon, ver checker/profile.go
+	profileConfig := checker.RegisterProfileFlags(flag.CommandLine)
+	flag.Parse()
+
+	var err error
+	if logger, err = logConfig.Logger(os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if prober, err = probeConfig.Prober(logger); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
 	// declara un array de urls para chequear
 	var websites = []string{
 		"http://ort.edu.uy",
@@ -93,9 +145,32 @@ func main() {
 		"http://ingsoft.gaston.com",
 	}
 
-	fmt.Printf("*****comienzo *****\n")
+	urls, err := inventory.URLs(websites)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	profiler, err := profileConfig.Start(logger)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	logger.Info("*****comienzo *****")
+	start := time.Now()
+
+	// con ctrl-c se cancelan las verificaciones que faltan
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	profileConfig.Loop(ctx, func() { CheckWebsites(ctx, urls) })
 
-	CheckWebsites(websites)
+	logger.Info("***** FIN *****", checker.KeyDuration, time.Since(start))
 
-	fmt.Printf("***** FIN *****")
+	if err := probeConfig.Close(); err != nil {
+		logger.Warn("no se pudo guardar la cache", checker.KeyError, err)
+	}
+	if err := profiler.Stop(); err != nil {
+		logger.Warn("no se pudieron guardar los perfiles", checker.KeyError, err)
+	}
 }
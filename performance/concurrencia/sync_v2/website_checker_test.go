@@ -0,0 +1,38 @@
+// pruebas de correctitud con un prober falso, se pueden ejecutar con el detector de carreras:
+// go test -race ./...
+
+package main
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"arqsoft/tacticas-arq-go/performance/concurrencia/checker"
+	"arqsoft/tacticas-arq-go/performance/concurrencia/checker/checkertest"
+)
+
+// strategy le indica a checkertest cómo reemplazar el logger y el prober de este ejemplo y qué función probar
+var strategy = checkertest.Strategy{
+	Use: func(l *slog.Logger, p checker.Prober) func() {
+		savedLogger, savedProber := logger, prober
+		logger, prober = l, p
+		return func() { logger, prober = savedLogger, savedProber }
+	},
+	Check: CheckWebsites,
+}
+
+// las pruebas que comparten todas las estrategias, ver checkertest.RunStrategyTests
+func TestCheckWebsites(t *testing.T) {
+	checkertest.RunStrategyTests(t, strategy)
+}
+
+// se lanza una gorutina por url, así que todas las verificaciones están en curso a la vez
+func TestCheckWebsitesStartsOneGoroutinePerURL(t *testing.T) {
+	urls, fake, _ := strategy.UseFakes(t, 50*time.Millisecond, 20)
+	CheckWebsites(context.Background(), urls)
+	if n := fake.MaxInFlight(); n != len(urls) {
+		t.Errorf("hubo %d verificaciones a la vez, se esperaban %d", n, len(urls))
+	}
+}